@@ -0,0 +1,143 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Scenario profile currently used by /api, swapped at runtime by POST /admin/profile
+type activeProfile struct {
+	Name    string          `json:"profile"`
+	Weights ScenarioWeights `json:"weights"`
+}
+
+var currentProfile atomic.Pointer[activeProfile]
+
+// Make a profile the one /api selects scenarios with
+func setActiveProfile(name string, weights ScenarioWeights) {
+	currentProfile.Store(&activeProfile{Name: name, Weights: weights})
+}
+
+// Weights /api should use right now, the configured ones until a profile is set
+func activeWeights() ScenarioWeights {
+	if p := currentProfile.Load(); p != nil {
+		return p.Weights
+	}
+	return cfg.ScenarioWeights
+}
+
+// Report whether the request carries the configured admin bearer token
+func validAdminToken(r *http.Request, token string) bool {
+	provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1
+}
+
+// Reject admin requests without the configured bearer token
+func adminAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if validAdminToken(r, cfg.AdminToken) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		requestID := requestIDFromContext(r.Context())
+		slog.Warn("Admin request rejected", "path", r.URL.Path, "request_id", requestID)
+
+		w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+		writeError(w, http.StatusUnauthorized, "Unauthorized", "Authentication required", "Provide the admin token as a Bearer token", requestID)
+	})
+}
+
+// Admin profile handler - switches the /api scenario profile without a restart
+func adminProfileHandler(w http.ResponseWriter, r *http.Request) {
+	requestID := requestIDFromContext(r.Context())
+	timestamp := time.Now().Format(time.RFC3339)
+
+	var body struct {
+		Profile string `json:"profile"`
+	}
+	if _, ok := decodeJSONBody(w, r, &body, `Expected a body like {"profile":"degraded"}`); !ok {
+		return
+	}
+
+	weights, ok := scenarioProfiles[body.Profile]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "Bad Request", "Unknown scenario profile", "Profile must be healthy, degraded or flaky", requestID)
+		return
+	}
+
+	setActiveProfile(body.Profile, weights)
+	slog.Info("Scenario profile changed", "profile", body.Profile, "weights", weights, "request_id", requestID)
+
+	writeJSON(w, http.StatusOK, SuccessResponse{
+		Status:    http.StatusOK,
+		Message:   "Scenario profile updated",
+		Data:      currentProfile.Load(),
+		RequestID: requestID,
+		Timestamp: timestamp,
+	})
+}
+
+// Admin log handler - writes one log record with the posted level, message and attributes,
+// for checking log routing and severity mapping end to end
+func adminLogHandler(w http.ResponseWriter, r *http.Request) {
+	requestID := requestIDFromContext(r.Context())
+
+	var body struct {
+		Level      string                 `json:"level"`
+		Message    string                 `json:"message"`
+		Attributes map[string]interface{} `json:"attributes"`
+	}
+	const expected = `Expected a body like {"level":"error","message":"...","attributes":{}}`
+	if _, ok := decodeJSONBody(w, r, &body, expected); !ok {
+		return
+	}
+	if body.Message == "" {
+		writeError(w, http.StatusBadRequest, "Bad Request", "Invalid JSON format", expected, requestID)
+		return
+	}
+
+	level := slog.LevelInfo
+	if body.Level != "" {
+		if err := level.UnmarshalText([]byte(body.Level)); err != nil {
+			writeError(w, http.StatusBadRequest, "Bad Request", "Invalid log level",
+				"Level must be debug, info, warn or error", requestID)
+			return
+		}
+	}
+
+	// Sorted keys keep the record stable for the same input
+	keys := make([]string, 0, len(body.Attributes))
+	for key := range body.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	posted := make([]any, 0, len(keys))
+	for _, key := range keys {
+		posted = append(posted, slog.Any(key, body.Attributes[key]))
+	}
+
+	// Posted attributes stay under their own group so they can't override source or request_id
+	slog.LogAttrs(r.Context(), level, body.Message,
+		slog.Group("attributes", posted...),
+		slog.String("source", "admin"),
+		slog.String("request_id", requestID),
+	)
+
+	writeJSON(w, http.StatusOK, SuccessResponse{
+		Status:  http.StatusOK,
+		Message: "Log record written",
+		Data: map[string]interface{}{
+			"level":   level.String(),
+			"message": body.Message,
+		},
+		RequestID: requestID,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const maxBatchSize = 10
+
+type BatchResult struct {
+	Status  int    `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Message string `json:"message"`
+	DelayMs int    `json:"delayMs"`
+}
+
+// Rank a status for picking a batch's worst result: any 5xx beats any 4xx, which
+// beats everything else, and the higher code wins within a class
+func batchSeverity(status int) int {
+	switch {
+	case status >= 500:
+		return 2000 + status
+	case status >= 400:
+		return 1000 + status
+	default:
+		return 0
+	}
+}
+
+// Batch handler - runs scenario selection ?n= times as if fanned out in parallel,
+// waits for the slowest pick and replies with the worst status of the batch
+func batchHandler(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := requestIDFromContext(r.Context())
+
+	n := 3
+	if value := r.URL.Query().Get("n"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxBatchSize {
+			slog.Warn("Invalid batch size parameter", "n", value, "request_id", requestID)
+			writeError(w, http.StatusBadRequest, "Bad Request", "Invalid request parameters",
+				fmt.Sprintf("n must be a number between 1 and %d", maxBatchSize), requestID)
+			return
+		}
+		n = parsed
+	}
+
+	weights := activeWeights()
+	results := make([]BatchResult, n)
+	worst, slowest, failed := http.StatusOK, 0, 0
+	for i := range results {
+		scenario := selectScenario(weights, rng)
+		delay := sampleDelay(delayRangeFor(scenario.Status))
+
+		results[i] = BatchResult{
+			Status:  scenario.Status,
+			Error:   scenario.Error,
+			Message: scenario.Message,
+			DelayMs: delay,
+		}
+		if batchSeverity(scenario.Status) > batchSeverity(worst) {
+			worst = scenario.Status
+		}
+		slowest = max(slowest, delay)
+		if scenario.Status >= 400 {
+			failed++
+		}
+	}
+
+	// The batch takes as long as its slowest member
+	if !sleepContext(r.Context(), time.Duration(slowest)*time.Millisecond) {
+		slog.Warn("Batch request canceled by client",
+			"status", statusClientClosedRequest, "outcome", "canceled", "size", n,
+			"elapsed", time.Since(start).String(), "request_id", requestID)
+		return
+	}
+
+	slog.Info("Batch request completed",
+		"status", worst, "class", statusClass(worst), "size", n, "delay_ms", slowest,
+		"duration", time.Since(start).String(), "request_id", requestID)
+
+	// Errors use the usual error shape with every result attached
+	if worst >= 400 {
+		response := newErrorResponse(worst, http.StatusText(worst), "Batch completed with errors",
+			fmt.Sprintf("%d of %d results failed", failed, n), requestID)
+		response.Results = results
+		writeJSON(w, worst, response)
+		return
+	}
+
+	// Redirects, 206, 204 and 304 would need headers or an empty body, so a batch
+	// without errors always reports 200
+	writeJSON(w, http.StatusOK, SuccessResponse{
+		Status:  http.StatusOK,
+		Message: "Batch completed",
+		Data: map[string]interface{}{
+			"results": results,
+			"size":    n,
+		},
+		RequestID: requestID,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
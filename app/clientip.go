@@ -0,0 +1,56 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net"
+	"net/http"
+)
+
+// How client IPs appear in logs, set with IP_MODE
+const (
+	ipModeFull   = "full"   // log the address as received
+	ipModeMasked = "masked" // zero the last IPv4 octet or all but the first 48 IPv6 bits
+	ipModeHashed = "hashed" // HMAC-SHA256 of the address keyed with IP_HASH_SALT
+	ipModeNone   = "none"   // leave the address out entirely
+)
+
+// Client address without the port, used as the rate limiter key
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// Client IP as an "ip" log attribute under the configured IP_MODE. In none mode it is
+// the empty Attr, which slog drops from the record.
+func ipAttr(ip string) slog.Attr {
+	switch cfg.IPMode {
+	case ipModeFull:
+		return slog.String("ip", ip)
+	case ipModeHashed:
+		mac := hmac.New(sha256.New, []byte(cfg.IPHashSalt))
+		mac.Write([]byte(ip))
+		return slog.String("ip", hex.EncodeToString(mac.Sum(nil))[:16])
+	case ipModeNone:
+		return slog.Attr{}
+	default:
+		return slog.String("ip", maskIP(ip))
+	}
+}
+
+// Zero the host part of an address, keeping enough to tell networks apart
+func maskIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return parsed.Mask(net.CIDRMask(48, 128)).String()
+}
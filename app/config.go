@@ -0,0 +1,604 @@
+package main
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ScenarioWeights sets how many weighted entries each response class gets in /api
+type ScenarioWeights struct {
+	Success     int `yaml:"success" json:"success"`
+	Redirect    int `yaml:"redirect" json:"redirect"`
+	ClientError int `yaml:"client_error" json:"client_error"`
+	ServerError int `yaml:"server_error" json:"server_error"`
+}
+
+// Check weights are non-negative and select at least one class
+func (w ScenarioWeights) validate() error {
+	if w.Success < 0 || w.Redirect < 0 || w.ClientError < 0 || w.ServerError < 0 {
+		return fmt.Errorf("weights must not be negative")
+	}
+	if w.Success+w.Redirect+w.ClientError+w.ServerError == 0 {
+		return fmt.Errorf("at least one weight must be greater than zero")
+	}
+	return nil
+}
+
+// Inclusive range of an artificial delay in milliseconds
+type DelayRange struct {
+	MinMs int `yaml:"min_ms" json:"min_ms"`
+	MaxMs int `yaml:"max_ms" json:"max_ms"`
+}
+
+// Delay ranges derived from the global range when none are configured: cache hits
+// (304) near zero, successes in the lower third and server errors in the top third.
+// Other responses keep the full range.
+func defaultStatusDelays(minMs, maxMs int) map[string]DelayRange {
+	third := (maxMs - minMs) / 3
+	return map[string]DelayRange{
+		"304": {0, minMs / 5},
+		"2xx": {minMs, minMs + third},
+		"5xx": {maxMs - third, maxMs},
+	}
+}
+
+// Parse "2xx=100-1000,304=0-20" into per-status delay ranges
+func parseStatusDelays(entries []string) (map[string]DelayRange, error) {
+	delays := make(map[string]DelayRange, len(entries))
+	for _, entry := range entries {
+		key, value, ok := strings.Cut(entry, "=")
+		minValue, maxValue, okRange := strings.Cut(value, "-")
+		if !ok || !okRange {
+			return nil, fmt.Errorf("entry %q must look like 5xx=2000-3000", entry)
+		}
+		minMs, errMin := strconv.Atoi(strings.TrimSpace(minValue))
+		maxMs, errMax := strconv.Atoi(strings.TrimSpace(maxValue))
+		if errMin != nil || errMax != nil {
+			return nil, fmt.Errorf("entry %q must use whole milliseconds", entry)
+		}
+		delays[strings.ToLower(strings.TrimSpace(key))] = DelayRange{minMs, maxMs}
+	}
+	return delays, validateStatusDelays(delays)
+}
+
+// Lower-case and trim keys so a config file accepts "5XX" just like STATUS_DELAYS does
+func normalizeStatusDelays(delays map[string]DelayRange) (map[string]DelayRange, error) {
+	if delays == nil {
+		return nil, nil
+	}
+	normalized := make(map[string]DelayRange, len(delays))
+	for key, d := range delays {
+		normalizedKey := strings.ToLower(strings.TrimSpace(key))
+		if _, dup := normalized[normalizedKey]; dup {
+			return nil, fmt.Errorf("key %q is listed more than once", normalizedKey)
+		}
+		normalized[normalizedKey] = d
+	}
+	return normalized, nil
+}
+
+// Check keys are a class ("2xx") or a status code ("304") and ranges are ordered
+func validateStatusDelays(delays map[string]DelayRange) error {
+	for key, d := range delays {
+		code, err := strconv.Atoi(key)
+		isClass := len(key) == 3 && key[0] >= '1' && key[0] <= '5' && key[1:] == "xx"
+		if !isClass && (err != nil || code < 100 || code > 599) {
+			return fmt.Errorf("key %q must be a status class like 5xx or a status code like 304", key)
+		}
+		if d.MinMs < 0 || d.MinMs > d.MaxMs {
+			return fmt.Errorf("range for %s must be non-negative with min not above max, got %d-%d", key, d.MinMs, d.MaxMs)
+		}
+	}
+	return nil
+}
+
+// Named weight presets selected with SCENARIO_PROFILE. Weights are percentages:
+//
+//	healthy:  94% 2xx, 2% 3xx,  3% 4xx,  1% 5xx
+//	degraded: 50% 2xx, 2% 3xx,  8% 4xx, 40% 5xx
+//	flaky:    40% 2xx, 5% 3xx, 30% 4xx, 25% 5xx
+var scenarioProfiles = map[string]ScenarioWeights{
+	"healthy":  {Success: 94, Redirect: 2, ClientError: 3, ServerError: 1},
+	"degraded": {Success: 50, Redirect: 2, ClientError: 8, ServerError: 40},
+	"flaky":    {Success: 40, Redirect: 5, ClientError: 30, ServerError: 25},
+}
+
+// Config holds runtime settings read from an optional config file and the environment.
+// File keys are the yaml tags below, and environment variables override file values.
+type Config struct {
+	Port string `yaml:"port"`
+
+	// Service identity reported by /version
+	ServiceName    string `yaml:"service_name"`
+	ServiceVersion string `yaml:"service_version"`
+	Environment    string `yaml:"environment"`
+
+	// Request headers /api copies into the response body, empty disables echoing
+	EchoHeaders []string `yaml:"echo_headers"`
+
+	// Range of the artificial /api delay in milliseconds
+	MinDelayMs int `yaml:"min_delay_ms"`
+	MaxDelayMs int `yaml:"max_delay_ms"`
+
+	// How delays are spread within a range: "uniform", "normal" (clustered mid-range)
+	// or "exponential" (mostly fast with a long tail)
+	DelayDistribution string `yaml:"delay_distribution"`
+
+	// Delay ranges for /api keyed by exact status ("304") or class ("5xx"), so errors
+	// can be slower than cache hits. An exact status wins over its class, and statuses
+	// with neither use MinDelayMs-MaxDelayMs. Unset derives defaultStatusDelays.
+	StatusDelays map[string]DelayRange `yaml:"status_delays"`
+
+	// Largest request body accepted on any route, enforced by bodyLimitMiddleware
+	MaxBodyBytes int64 `yaml:"max_body_bytes"`
+
+	// Longest request URI and largest header block before real 414/431 responses
+	MaxURILength   int `yaml:"max_uri_length"`
+	MaxHeaderBytes int `yaml:"max_header_bytes"`
+
+	// Relative weights of the /api response classes. A named profile replaces the
+	// weights, and SCENARIO_WEIGHT_* variables still adjust single classes on top.
+	ScenarioProfile string          `yaml:"scenario_profile"`
+	ScenarioWeights ScenarioWeights `yaml:"scenario_weights"`
+
+	// Path prefix all routes are mounted under (e.g. "/demo"), empty serves them at the root
+	BasePath string `yaml:"base_path"`
+
+	// Bearer token for the /admin endpoints, empty leaves them unregistered
+	AdminToken string `yaml:"admin_token"`
+
+	// Seed for scenario and delay picks, time-based unless RANDOM_SEED is set
+	RandomSeed int64 `yaml:"random_seed"`
+
+	// Per-client request rate limit, 0 disables limiting
+	RateLimitRPS   float64 `yaml:"rate_limit_rps"`
+	RateLimitBurst int     `yaml:"rate_limit_burst"`
+
+	// How client IPs are logged ("full", "masked", "hashed" or "none") and the HMAC key for "hashed"
+	IPMode     string `yaml:"ip_mode"`
+	IPHashSalt string `yaml:"ip_hash_salt"`
+
+	// Origins allowed to call the API from a browser, "*" allows any, empty disables CORS
+	CORSAllowedOrigins []string `yaml:"cors_allowed_origins"`
+
+	// Log output format ("json" or "text") and minimum level
+	LogFormat string     `yaml:"log_format"`
+	LogLevel  slog.Level `yaml:"log_level"`
+
+	// Probability (0.0-1.0) that /api returns a random 5xx regardless of weights
+	ErrorInjectionRate float64 `yaml:"error_injection_rate"`
+
+	// Certificate and key for serving HTTPS, both or neither. Plain HTTP when neither is set
+	TLSCertFile string `yaml:"tls_cert_file"`
+	TLSKeyFile  string `yaml:"tls_key_file"`
+
+	// HTTP server timeouts. WriteTimeout covers the whole handler run, including
+	// the artificial /api delay and /api/stream chunk pauses, so it must stay above
+	// the slowest delay or slow scenarios get cut off before they respond. A delay forced
+	// with ?delay= is clamped to a second under WriteTimeout for the same reason.
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+
+	// Time between /readyz turning 503 and the listener closing on shutdown, so load
+	// balancers can stop routing here before in-flight requests drain. 0 closes at once.
+	ShutdownDelay time.Duration `yaml:"shutdown_delay"`
+}
+
+var cfg = defaultConfig()
+
+// Default settings used when nothing is set in the environment
+func defaultConfig() *Config {
+	return &Config{
+		Port:           "8080",
+		ServiceName:    "otel-lgtm-api",
+		ServiceVersion: "dev",
+		Environment:    "development",
+		MinDelayMs:     100,
+		MaxDelayMs:     3000,
+		MaxBodyBytes:   1 << 20,
+		MaxURILength:   2048,
+		MaxHeaderBytes: 8192,
+		// 60% success, 5% redirect, 25% client error, 10% server error
+		ScenarioWeights: ScenarioWeights{
+			Success:     12,
+			Redirect:    1,
+			ClientError: 5,
+			ServerError: 2,
+		},
+		RandomSeed:     time.Now().UnixNano(),
+		RateLimitBurst: 20,
+		IPMode:         ipModeMasked,
+		LogFormat:      "json",
+		LogLevel:       slog.LevelInfo,
+
+		DelayDistribution: "uniform",
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+}
+
+// Build config from environment variables on top of the defaults
+func loadConfig() *Config {
+	c := defaultConfig()
+	c.applyEnv()
+	return c
+}
+
+// Build config from a YAML or JSON file on top of the defaults, then apply environment overrides
+func LoadConfigFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	// JSON is valid YAML, so one decoder handles both formats
+	c := defaultConfig()
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	if c.StatusDelays, err = normalizeStatusDelays(c.StatusDelays); err != nil {
+		return nil, fmt.Errorf("invalid config in %s: status_delays: %w", path, err)
+	}
+
+	c.applyEnv()
+
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config in %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Check the TLS certificate and key are set together. Half a TLS setup must fail
+// rather than quietly serve plain HTTP.
+func (c *Config) validateTLS() error {
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("tls_cert_file and tls_key_file must be set together, got cert %q and key %q", c.TLSCertFile, c.TLSKeyFile)
+	}
+	return nil
+}
+
+// Check settings that have no safe fallback when they come from a config file
+func (c *Config) validate() error {
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("port must be a number between 1 and 65535, got %q", c.Port)
+	}
+	if c.ServiceName == "" {
+		return fmt.Errorf("service_name must not be empty")
+	}
+	if c.MinDelayMs < 0 || c.MinDelayMs > c.MaxDelayMs {
+		return fmt.Errorf("min_delay_ms (%d) must be non-negative and not above max_delay_ms (%d)", c.MinDelayMs, c.MaxDelayMs)
+	}
+	if c.MaxBodyBytes <= 0 || c.MaxURILength <= 0 || c.MaxHeaderBytes <= 0 {
+		return fmt.Errorf("max_body_bytes, max_uri_length and max_header_bytes must be positive")
+	}
+	if _, ok := scenarioProfiles[c.ScenarioProfile]; c.ScenarioProfile != "" && !ok {
+		return fmt.Errorf("scenario_profile must be healthy, degraded or flaky, got %q", c.ScenarioProfile)
+	}
+	switch c.DelayDistribution {
+	case "uniform", "normal", "exponential":
+	default:
+		return fmt.Errorf("delay_distribution must be uniform, normal or exponential, got %q", c.DelayDistribution)
+	}
+	if err := validateStatusDelays(c.StatusDelays); err != nil {
+		return fmt.Errorf("status_delays: %w", err)
+	}
+	if err := c.ScenarioWeights.validate(); err != nil {
+		return fmt.Errorf("scenario_weights: %w", err)
+	}
+	if c.RateLimitRPS < 0 || c.RateLimitBurst < 1 {
+		return fmt.Errorf("rate_limit_rps must not be negative and rate_limit_burst must be at least 1")
+	}
+	switch c.IPMode {
+	case ipModeFull, ipModeMasked, ipModeHashed, ipModeNone:
+	default:
+		return fmt.Errorf("ip_mode must be full, masked, hashed or none, got %q", c.IPMode)
+	}
+	if c.LogFormat != "json" && c.LogFormat != "text" {
+		return fmt.Errorf("log_format must be json or text, got %q", c.LogFormat)
+	}
+	if c.ErrorInjectionRate < 0 || c.ErrorInjectionRate > 1 {
+		return fmt.Errorf("error_injection_rate must be between 0 and 1, got %g", c.ErrorInjectionRate)
+	}
+	if c.ReadHeaderTimeout <= 0 || c.ReadTimeout <= 0 || c.WriteTimeout <= 0 || c.IdleTimeout <= 0 {
+		return fmt.Errorf("server timeouts must be positive durations")
+	}
+	if err := c.validateTLS(); err != nil {
+		return err
+	}
+	if c.ShutdownDelay < 0 {
+		return fmt.Errorf("shutdown_delay must not be negative, got %s", c.ShutdownDelay)
+	}
+	return nil
+}
+
+// Override settings with any environment variables that are set
+func (c *Config) applyEnv() {
+	c.Port = getEnv("PORT", c.Port)
+	c.ServiceName = getEnv("OTEL_SERVICE_NAME", c.ServiceName)
+	c.ServiceVersion = getEnv("OTEL_SERVICE_VERSION", c.ServiceVersion)
+	c.Environment = getEnv("ENVIRONMENT", c.Environment)
+	c.EchoHeaders = getEnvList("ECHO_HEADERS", c.EchoHeaders)
+
+	minDelay := getEnvInt("API_MIN_DELAY_MS", c.MinDelayMs)
+	maxDelay := getEnvInt("API_MAX_DELAY_MS", c.MaxDelayMs)
+	if minDelay < 0 || minDelay > maxDelay {
+		slog.Warn("Invalid API delay range, using defaults",
+			"min_delay_ms", minDelay, "max_delay_ms", maxDelay,
+			"default_min_delay_ms", c.MinDelayMs, "default_max_delay_ms", c.MaxDelayMs)
+	} else {
+		c.MinDelayMs = minDelay
+		c.MaxDelayMs = maxDelay
+	}
+
+	switch distribution := getEnv("DELAY_DISTRIBUTION", c.DelayDistribution); distribution {
+	case "uniform", "normal", "exponential":
+		c.DelayDistribution = distribution
+	default:
+		slog.Warn("Invalid DELAY_DISTRIBUTION, using default", "value", distribution, "default", c.DelayDistribution)
+	}
+
+	// STATUS_DELAYS="2xx=100-1000,304=0-20,5xx=2000-3000" replaces the per-status ranges, "none" turns them off
+	if value := getEnvList("STATUS_DELAYS", nil); len(value) == 1 && value[0] == "none" {
+		c.StatusDelays = map[string]DelayRange{}
+	} else if value != nil {
+		if delays, err := parseStatusDelays(value); err != nil {
+			slog.Warn("Invalid STATUS_DELAYS, using defaults", "value", strings.Join(value, ","), "error", err)
+		} else {
+			c.StatusDelays = delays
+		}
+	}
+	if c.StatusDelays == nil {
+		c.StatusDelays = defaultStatusDelays(c.MinDelayMs, c.MaxDelayMs)
+	}
+
+	if maxBody := getEnvInt("MAX_BODY_BYTES", int(c.MaxBodyBytes)); maxBody > 0 {
+		c.MaxBodyBytes = int64(maxBody)
+	} else {
+		slog.Warn("Invalid MAX_BODY_BYTES, using default", "value", maxBody, "default", c.MaxBodyBytes)
+	}
+
+	if maxURI := getEnvInt("MAX_URI_LENGTH", c.MaxURILength); maxURI > 0 {
+		c.MaxURILength = maxURI
+	} else {
+		slog.Warn("Invalid MAX_URI_LENGTH, using default", "value", maxURI, "default", c.MaxURILength)
+	}
+
+	if maxHeader := getEnvInt("MAX_HEADER_BYTES", c.MaxHeaderBytes); maxHeader > 0 {
+		c.MaxHeaderBytes = maxHeader
+	} else {
+		slog.Warn("Invalid MAX_HEADER_BYTES, using default", "value", maxHeader, "default", c.MaxHeaderBytes)
+	}
+
+	if profile := getEnv("SCENARIO_PROFILE", c.ScenarioProfile); profile != "" {
+		if weights, ok := scenarioProfiles[profile]; ok {
+			c.ScenarioProfile = profile
+			c.ScenarioWeights = weights
+		} else {
+			slog.Warn("Unknown SCENARIO_PROFILE, using default weights", "value", profile, "profiles", "healthy, degraded, flaky")
+		}
+	}
+
+	weights := ScenarioWeights{
+		Success:     getEnvInt("SCENARIO_WEIGHT_2XX", c.ScenarioWeights.Success),
+		Redirect:    getEnvInt("SCENARIO_WEIGHT_3XX", c.ScenarioWeights.Redirect),
+		ClientError: getEnvInt("SCENARIO_WEIGHT_4XX", c.ScenarioWeights.ClientError),
+		ServerError: getEnvInt("SCENARIO_WEIGHT_5XX", c.ScenarioWeights.ServerError),
+	}
+	if err := weights.validate(); err != nil {
+		slog.Warn("Invalid scenario weights, using defaults", "weights", weights, "error", err)
+	} else {
+		c.ScenarioWeights = weights
+	}
+
+	// Normalize to a leading slash and no trailing slash, so "demo/" becomes "/demo"
+	if basePath := strings.Trim(getEnv("BASE_PATH", c.BasePath), "/"); basePath != "" {
+		c.BasePath = "/" + basePath
+	} else {
+		c.BasePath = ""
+	}
+
+	c.AdminToken = getEnv("ADMIN_TOKEN", c.AdminToken)
+
+	c.RandomSeed = int64(getEnvInt("RANDOM_SEED", int(c.RandomSeed)))
+
+	rps := getEnvFloat("RATE_LIMIT_RPS", c.RateLimitRPS)
+	burst := getEnvInt("RATE_LIMIT_BURST", c.RateLimitBurst)
+	if rps < 0 || burst < 1 {
+		slog.Warn("Invalid rate limit, using defaults", "rps", rps, "burst", burst)
+	} else {
+		c.RateLimitRPS = rps
+		c.RateLimitBurst = burst
+	}
+
+	switch mode := getEnv("IP_MODE", c.IPMode); mode {
+	case ipModeFull, ipModeMasked, ipModeHashed, ipModeNone:
+		c.IPMode = mode
+	default:
+		slog.Warn("Invalid IP_MODE, using default", "value", mode, "default", c.IPMode)
+	}
+	c.IPHashSalt = getEnv("IP_HASH_SALT", c.IPHashSalt)
+	if c.IPMode == ipModeHashed && c.IPHashSalt == "" {
+		slog.Warn("IP_MODE is hashed but IP_HASH_SALT is empty, using a random salt so hashes change on restart")
+		salt := make([]byte, 16)
+		crand.Read(salt)
+		c.IPHashSalt = hex.EncodeToString(salt)
+	}
+
+	c.CORSAllowedOrigins = getEnvList("CORS_ALLOWED_ORIGINS", c.CORSAllowedOrigins)
+
+	switch format := getEnv("LOG_FORMAT", c.LogFormat); format {
+	case "json", "text":
+		c.LogFormat = format
+	default:
+		slog.Warn("Invalid LOG_FORMAT, using default", "value", format, "default", c.LogFormat)
+	}
+
+	if value := os.Getenv("LOG_LEVEL"); value != "" {
+		if err := c.LogLevel.UnmarshalText([]byte(value)); err != nil {
+			slog.Warn("Invalid LOG_LEVEL, using default", "value", value, "default", c.LogLevel.String())
+		}
+	}
+
+	if rate := getEnvFloat("ERROR_INJECTION_RATE", c.ErrorInjectionRate); rate >= 0 && rate <= 1 {
+		c.ErrorInjectionRate = rate
+	} else {
+		slog.Warn("ERROR_INJECTION_RATE must be between 0 and 1, using default", "value", rate, "default", c.ErrorInjectionRate)
+	}
+
+	c.TLSCertFile = getEnv("TLS_CERT_FILE", c.TLSCertFile)
+	c.TLSKeyFile = getEnv("TLS_KEY_FILE", c.TLSKeyFile)
+
+	c.ReadHeaderTimeout = getEnvDuration("SERVER_READ_HEADER_TIMEOUT", c.ReadHeaderTimeout)
+	c.ReadTimeout = getEnvDuration("SERVER_READ_TIMEOUT", c.ReadTimeout)
+	c.WriteTimeout = getEnvDuration("SERVER_WRITE_TIMEOUT", c.WriteTimeout)
+	c.IdleTimeout = getEnvDuration("SERVER_IDLE_TIMEOUT", c.IdleTimeout)
+	c.ShutdownDelay = getEnvDuration("SERVER_SHUTDOWN_DELAY", c.ShutdownDelay)
+
+	// Keep the write timeout above the slowest /api response
+	slowestMs := c.MaxDelayMs
+	for _, d := range c.StatusDelays {
+		slowestMs = max(slowestMs, d.MaxMs)
+	}
+	slowest := time.Duration(slowestMs) * time.Millisecond
+	if c.WriteTimeout <= slowest {
+		writeTimeout := slowest + 5*time.Second
+		slog.Warn("SERVER_WRITE_TIMEOUT does not exceed the max API delay, raising it",
+			"write_timeout", c.WriteTimeout.String(), "max_delay", slowest.String(), "new_write_timeout", writeTimeout.String())
+		c.WriteTimeout = writeTimeout
+	}
+}
+
+// Log the effective settings as structured fields, with secrets redacted
+func (c *Config) LogValue() slog.Value {
+	redact := func(secret string) string {
+		if secret == "" {
+			return ""
+		}
+		return "[REDACTED]"
+	}
+
+	return slog.GroupValue(
+		slog.String("port", c.Port),
+		slog.String("service_name", c.ServiceName),
+		slog.String("service_version", c.ServiceVersion),
+		slog.String("environment", c.Environment),
+		slog.Any("echo_headers", c.EchoHeaders),
+		slog.Int("min_delay_ms", c.MinDelayMs),
+		slog.Int("max_delay_ms", c.MaxDelayMs),
+		slog.String("delay_distribution", c.DelayDistribution),
+		slog.Any("status_delays", c.StatusDelays),
+		slog.Int64("max_body_bytes", c.MaxBodyBytes),
+		slog.Int("max_uri_length", c.MaxURILength),
+		slog.Int("max_header_bytes", c.MaxHeaderBytes),
+		slog.String("scenario_profile", c.ScenarioProfile),
+		slog.Group("scenario_weights",
+			"success", c.ScenarioWeights.Success,
+			"redirect", c.ScenarioWeights.Redirect,
+			"client_error", c.ScenarioWeights.ClientError,
+			"server_error", c.ScenarioWeights.ServerError),
+		slog.String("base_path", c.BasePath),
+		slog.String("admin_token", redact(c.AdminToken)),
+		slog.Int64("random_seed", c.RandomSeed),
+		slog.Float64("rate_limit_rps", c.RateLimitRPS),
+		slog.Int("rate_limit_burst", c.RateLimitBurst),
+		slog.String("ip_mode", c.IPMode),
+		slog.String("ip_hash_salt", redact(c.IPHashSalt)),
+		slog.Any("cors_allowed_origins", c.CORSAllowedOrigins),
+		slog.String("log_format", c.LogFormat),
+		slog.String("log_level", c.LogLevel.String()),
+		slog.Float64("error_injection_rate", c.ErrorInjectionRate),
+		slog.String("tls_cert_file", c.TLSCertFile),
+		slog.String("tls_key_file", c.TLSKeyFile),
+		slog.String("read_header_timeout", c.ReadHeaderTimeout.String()),
+		slog.String("read_timeout", c.ReadTimeout.String()),
+		slog.String("write_timeout", c.WriteTimeout.String()),
+		slog.String("idle_timeout", c.IdleTimeout.String()),
+		slog.String("shutdown_delay", c.ShutdownDelay.String()),
+	)
+}
+
+// Get string value from environment or fall back to default
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// Get integer value from environment or fall back to default
+func getEnvInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Warn("Invalid environment value, using default", "key", key, "value", value, "default", fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// Get float value from environment or fall back to default
+func getEnvFloat(key string, fallback float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		slog.Warn("Invalid environment value, using default", "key", key, "value", value, "default", fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// Get positive duration (e.g. "15s") from environment or fall back to default
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		slog.Warn("Invalid environment value, using default", "key", key, "value", value, "default", fallback.String())
+		return fallback
+	}
+	return parsed
+}
+
+// Get comma-separated list from environment or fall back to default
+func getEnvList(key string, fallback []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -3,3 +3,7 @@ module github.com/dinocodes/otel-lgtm/server
 go 1.23.6
 
 require github.com/gorilla/mux v1.8.1
+
+require golang.org/x/time v0.8.0
+
+require gopkg.in/yaml.v3 v3.0.1
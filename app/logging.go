@@ -0,0 +1,30 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+)
+
+// Create the application logger writing to stdout in the configured format and level
+func newLogger(format string, level slog.Level) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	if format == "text" {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	}
+
+	return slog.New(handler)
+}
+
+// Create the logger used before the config is loaded, from LOG_FORMAT and LOG_LEVEL
+// alone. Invalid values fall back quietly here, loading the config reports them.
+func newStartupLogger() *slog.Logger {
+	level := slog.LevelInfo
+	if err := level.UnmarshalText([]byte(os.Getenv("LOG_LEVEL"))); err != nil {
+		level = slog.LevelInfo
+	}
+	return newLogger(os.Getenv("LOG_FORMAT"), level)
+}
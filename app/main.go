@@ -1,14 +1,23 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	cryptorand "crypto/rand"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
-	"math/rand"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"runtime"
+	"runtime/debug"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -17,21 +26,31 @@ import (
 
 // Response structures
 type SuccessResponse struct {
-	Status    int         `json:"status"`
-	Message   string      `json:"message"`
-	Data      interface{} `json:"data,omitempty"`
-	RequestID string      `json:"requestId"`
-	Timestamp string      `json:"timestamp"`
+	Status        int               `json:"status"`
+	Message       string            `json:"message"`
+	Data          interface{}       `json:"data,omitempty"`
+	EchoedHeaders map[string]string `json:"echoedHeaders,omitempty"`
+	RequestID     string            `json:"requestId"`
+	Timestamp     string            `json:"timestamp"`
 }
 
 type ErrorResponse struct {
-	Status    int    `json:"status"`
-	Error     string `json:"error"`
-	Message   string `json:"message"`
-	Details   string `json:"details,omitempty"`
-	Location  string `json:"location,omitempty"`
-	RequestID string `json:"requestId"`
-	Timestamp string `json:"timestamp"`
+	Status        int               `json:"status"`
+	Error         string            `json:"error"`
+	Message       string            `json:"message"`
+	Details       string            `json:"details,omitempty"`
+	Location      string            `json:"location,omitempty"`
+	Errors        []FieldError      `json:"errors,omitempty"`
+	EchoedHeaders map[string]string `json:"echoedHeaders,omitempty"`
+	Results       []BatchResult     `json:"results,omitempty"`
+	RequestID     string            `json:"requestId"`
+	Timestamp     string            `json:"timestamp"`
+}
+
+// One invalid input field in a validation error response
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 type HealthResponse struct {
@@ -44,56 +63,245 @@ type RootResponse struct {
 	Message string `json:"message"`
 }
 
-type NotFoundResponse struct {
-	Error     string `json:"error"`
-	Message   string `json:"message"`
-	Timestamp string `json:"timestamp"`
+type VersionResponse struct {
+	ServiceName    string `json:"service.name"`
+	ServiceVersion string `json:"service.version"`
+	Environment    string `json:"environment"`
+	GoVersion      string `json:"goVersion"`
+	VCSRevision    string `json:"vcsRevision,omitempty"`
+	VCSTime        string `json:"vcsTime,omitempty"`
+	VCSModified    bool   `json:"vcsModified,omitempty"`
 }
 
-// Scenario represents a response scenario
-type Scenario struct {
-	Status   int
-	Error    string
-	Message  string
-	Details  string
-	Location string
-	Data     interface{}
+type StreamChunk struct {
+	Chunk     int    `json:"chunk"`
+	Total     int    `json:"total"`
+	RequestID string `json:"requestId"`
+	Timestamp string `json:"timestamp"`
 }
 
 var startTime = time.Now()
 
-// Generate random request ID
+// Non-standard status (nginx convention) logged when the client disconnects first
+const statusClientClosedRequest = 499
+
+// Stream endpoint limits
+const (
+	maxStreamChunks  = 100
+	streamChunkDelay = 200 * time.Millisecond
+)
+
+// Generate random request ID as a version 4 UUID. It comes from crypto/rand rather
+// than rng, so replicas sharing RANDOM_SEED don't repeat IDs and probe traffic
+// doesn't shift the seeded scenario sequence.
 func generateRequestID() string {
-	const charset = "abcdefghijklmnopqrstuvwxyz0123456789"
-	b := make([]byte, 13)
-	for i := range b {
-		b[i] = charset[rand.Intn(len(charset))]
+	var b [16]byte
+	if _, err := cryptorand.Read(b[:]); err != nil {
+		panic(err)
 	}
-	return string(b)
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
 }
 
-// Root route handler - simplified
-func rootHandler(w http.ResponseWriter, r *http.Request) {
-	log.Printf("Root endpoint accessed")
+// Copy allow-listed request headers for echoing back, nil when none are present
+func echoHeaders(r *http.Request) map[string]string {
+	var echoed map[string]string
+	for _, name := range cfg.EchoHeaders {
+		value := r.Header.Get(name)
+		if value == "" {
+			continue
+		}
+		if echoed == nil {
+			echoed = make(map[string]string)
+		}
+		echoed[http.CanonicalHeaderKey(name)] = value
+	}
+	return echoed
+}
+
+// Delay range for a response status: the exact code first, then its class, then the global range
+func delayRangeFor(status int) DelayRange {
+	if d, ok := cfg.StatusDelays[strconv.Itoa(status)]; ok {
+		return d
+	}
+	if d, ok := cfg.StatusDelays[statusClass(status)]; ok {
+		return d
+	}
+	return DelayRange{cfg.MinDelayMs, cfg.MaxDelayMs}
+}
+
+// Longest delay ?delay= may force, a second under WriteTimeout so the response still gets written
+func maxRequestedDelayMs() int {
+	return max(int((cfg.WriteTimeout - time.Second).Milliseconds()), 0)
+}
+
+// Delay in milliseconds from ?delay= or a random pick within the range for the response status.
+// Forced delays are clamped to maxRequestedDelayMs so a client can't hold a handler indefinitely.
+func requestedDelay(r *http.Request, status int) int {
+	if value := r.URL.Query().Get("delay"); value != "" {
+		delay, err := strconv.Atoi(value)
+		if err == nil && delay >= 0 {
+			if limit := maxRequestedDelayMs(); delay > limit {
+				slog.Warn("Clamping delay parameter", "delay", value, "max_delay_ms", limit)
+				return limit
+			}
+			return delay
+		}
+		slog.Warn("Ignoring invalid delay parameter", "delay", value)
+	}
+	return sampleDelay(delayRangeFor(status))
+}
+
+// Pick a delay within d following the configured DELAY_DISTRIBUTION, clamped to the range
+func sampleDelay(d DelayRange) int {
+	spread := float64(d.MaxMs - d.MinMs)
+	var delay float64
+	switch cfg.DelayDistribution {
+	case "normal":
+		// Centered in the range with about 99.7% of picks inside it
+		delay = float64(d.MinMs) + spread/2 + rng.NormFloat64()*spread/6
+	case "exponential":
+		// Mostly near the minimum with a long tail towards the maximum
+		delay = float64(d.MinMs) + rng.ExpFloat64()*spread/5
+	default:
+		return rng.Intn(d.MaxMs-d.MinMs+1) + d.MinMs
+	}
+	return min(max(int(delay), d.MinMs), d.MaxMs)
+}
+
+// Wait for d unless the request context ends first, reporting whether the wait completed
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Build the standard error body. Every error response goes through here so they
+// all share one shape, including the request ID and timestamp.
+func newErrorResponse(status int, code, message, details, requestID string) ErrorResponse {
+	return ErrorResponse{
+		Status:    status,
+		Error:     code,
+		Message:   message,
+		Details:   details,
+		RequestID: requestID,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+}
+
+// Write the standard error body with the given status
+func writeError(w http.ResponseWriter, status int, code, message, details, requestID string) {
+	writeJSON(w, status, newErrorResponse(status, code, message, details, requestID))
+}
+
+// Read a JSON request body into v, reporting its size and whether it decoded. Failures
+// are answered here: 413 once the body runs past the bodyLimitMiddleware cap, which
+// surfaces as *http.MaxBytesError, otherwise 400 with details.
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, v interface{}, details string) (int, bool) {
+	requestID := requestIDFromContext(r.Context())
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			slog.Warn("Request body too large", "path", r.URL.Path, "limit_bytes", maxBytesErr.Limit, "request_id", requestID)
+			writePayloadTooLarge(w, requestID, maxBytesErr.Limit)
+			return 0, false
+		}
+
+		slog.Error("Failed to read request body", "path", r.URL.Path, "error", err, "request_id", requestID)
+
+		writeError(w, http.StatusBadRequest, "Bad Request", "Unable to read request body", err.Error(), requestID)
+		return 0, false
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		slog.Warn("Invalid JSON request body", "path", r.URL.Path, "error", err, "size_bytes", len(body), "request_id", requestID)
+
+		writeError(w, http.StatusBadRequest, "Bad Request", "Invalid JSON format", details, requestID)
+		return len(body), false
+	}
+	return len(body), true
+}
+
+// Encode v as JSON and write it with the given status. The body is encoded into a
+// buffer first so a failed encode turns into a clean 500 instead of a truncated body.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		requestID := w.Header().Get("X-Request-ID")
+		slog.Error("Failed to encode response", "error", err, "status", status, "request_id", requestID)
+
+		// Drop headers meant for the original response
+		w.Header().Del("Location")
+		w.Header().Del("Retry-After")
+
+		buf.Reset()
+		json.NewEncoder(&buf).Encode(ErrorResponse{
+			Status:    http.StatusInternalServerError,
+			Error:     "Internal Server Error",
+			Message:   "Failed to encode response",
+			RequestID: requestID,
+			Timestamp: time.Now().Format(time.RFC3339),
+		})
+		status = http.StatusInternalServerError
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// Root route handler - simplified
+func rootHandler(w http.ResponseWriter, r *http.Request) {
+	slog.Info("Root endpoint accessed")
 
 	response := RootResponse{
 		Message: "App is running",
 	}
 
-	json.NewEncoder(w).Encode(response)
+	writeJSON(w, http.StatusOK, response)
+}
+
+// Version handler - service metadata plus Go build info
+func versionHandler(w http.ResponseWriter, r *http.Request) {
+	response := VersionResponse{
+		ServiceName:    cfg.ServiceName,
+		ServiceVersion: cfg.ServiceVersion,
+		Environment:    cfg.Environment,
+		GoVersion:      runtime.Version(),
+	}
+
+	// VCS details are only stamped when built from a git checkout
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				response.VCSRevision = setting.Value
+			case "vcs.time":
+				response.VCSTime = setting.Value
+			case "vcs.modified":
+				response.VCSModified = setting.Value == "true"
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, response)
 }
 
 // Health check handler - simplified
 func healthHandler(w http.ResponseWriter, r *http.Request) {
 	uptime := time.Since(startTime).Seconds()
 
-	log.Printf("Health check accessed, uptime: %.2f seconds", uptime)
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	slog.Info("Health check accessed", "uptime_seconds", uptime)
 
 	response := HealthResponse{
 		Status:    "healthy",
@@ -101,181 +309,56 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 		Uptime:    uptime,
 	}
 
-	json.NewEncoder(w).Encode(response)
+	writeJSON(w, http.StatusOK, response)
 }
 
 // API handler with business logic instrumentation only
 func apiHandler(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
+	requestID := requestIDFromContext(r.Context())
 
-	// Random delay between 100ms to 3000ms - business logic timing
-	delay := rand.Intn(2900) + 100
-	time.Sleep(time.Duration(delay) * time.Millisecond)
-
-	// Define all response scenarios (same as before)
-	scenarios := []Scenario{
-		// 2xx Success responses
-		{200, "", "Success response", "", "", map[string]interface{}{
-			"timestamp": time.Now().Format(time.RFC3339),
-			"delay":     fmt.Sprintf("%dms", delay),
-		}},
-		{200, "", "Data retrieved successfully", "", "", map[string]interface{}{
-			"users": []string{"Alice", "Bob", "Charlie"},
-			"delay": fmt.Sprintf("%dms", delay),
-		}},
-		{200, "", "Search results found", "", "", map[string]interface{}{
-			"results": []map[string]interface{}{
-				{"id": 1, "name": "Product A", "price": 29.99},
-				{"id": 2, "name": "Product B", "price": 49.99},
-			},
-			"total": 2,
-			"delay": fmt.Sprintf("%dms", delay),
-		}},
-		{201, "", "Resource created successfully", "", "", map[string]interface{}{
-			"id":    rand.Intn(1000),
-			"delay": fmt.Sprintf("%dms", delay),
-		}},
-		{201, "", "User account created", "", "", map[string]interface{}{
-			"userId":   rand.Intn(10000),
-			"username": fmt.Sprintf("user_%d", rand.Intn(1000)),
-			"delay":    fmt.Sprintf("%dms", delay),
-		}},
-		{202, "", "Request accepted for processing", "", "", map[string]interface{}{
-			"jobId":  generateRequestID(),
-			"status": "queued",
-			"delay":  fmt.Sprintf("%dms", delay),
-		}},
-		{204, "", "No content - operation successful", "", "", nil},
-		{206, "", "Partial content delivered", "", "", map[string]interface{}{
-			"range":         "bytes 0-1023/2048",
-			"contentLength": 1024,
-			"delay":         fmt.Sprintf("%dms", delay),
-		}},
-
-		// 3xx Redirection responses
-		{301, "", "Moved permanently", "", "/api/v2/endpoint", map[string]interface{}{
-			"redirect": true,
-			"delay":    fmt.Sprintf("%dms", delay),
-		}},
-		{302, "", "Found - temporary redirect", "", "/api/temp-endpoint", map[string]interface{}{
-			"redirect": true,
-			"delay":    fmt.Sprintf("%dms", delay),
-		}},
-		{304, "", "Not modified", "", "", map[string]interface{}{
-			"cached": true,
-			"delay":  fmt.Sprintf("%dms", delay),
-		}},
-		{307, "", "Temporary redirect", "", "/api/v1/fallback", map[string]interface{}{
-			"redirect": true,
-			"delay":    fmt.Sprintf("%dms", delay),
-		}},
-		{308, "", "Permanent redirect", "", "/api/v3/endpoint", map[string]interface{}{
-			"redirect": true,
-			"delay":    fmt.Sprintf("%dms", delay),
-		}},
-
-		// 4xx Client error responses
-		{400, "Bad Request", "Invalid request parameters", "Missing required field 'email'", "", nil},
-		{400, "Bad Request", "Invalid JSON format", "Malformed JSON in request body", "", nil},
-		{401, "Unauthorized", "Authentication required", "Please provide a valid API key", "", nil},
-		{401, "Unauthorized", "Token expired", "JWT token has expired, please refresh", "", nil},
-		{402, "Payment Required", "Subscription expired", "Please upgrade your plan to continue", "", nil},
-		{403, "Forbidden", "Access denied", "Insufficient permissions for this resource", "", nil},
-		{403, "Forbidden", "IP address blocked", "Your IP has been temporarily blocked", "", nil},
-		{404, "Not Found", "Resource not found", "The requested endpoint does not exist", "", nil},
-		{404, "Not Found", "User not found", fmt.Sprintf("User with ID %d does not exist", rand.Intn(1000)), "", nil},
-		{405, "Method Not Allowed", "HTTP method not supported", "Only GET and POST methods are allowed", "", nil},
-		{406, "Not Acceptable", "Content type not acceptable", "Server cannot produce content matching Accept header", "", nil},
-		{408, "Request Timeout", "Request took too long", "Client did not send request within timeout period", "", nil},
-		{409, "Conflict", "Resource conflict", "Email address already exists", "", nil},
-		{410, "Gone", "Resource no longer available", "This API version has been deprecated", "", nil},
-		{411, "Length Required", "Content-Length header required", "Request must include Content-Length header", "", nil},
-		{412, "Precondition Failed", "Precondition not met", "If-Match header condition failed", "", nil},
-		{413, "Payload Too Large", "Request entity too large", "File size exceeds 10MB limit", "", nil},
-		{414, "URI Too Long", "Request URI too long", "URL exceeds maximum length of 2048 characters", "", nil},
-		{415, "Unsupported Media Type", "Media type not supported", "Content-Type 'text/plain' not supported", "", nil},
-		{416, "Range Not Satisfiable", "Requested range not satisfiable", "Range header specifies invalid byte range", "", nil},
-		{417, "Expectation Failed", "Expectation cannot be met", "Expect header requirements cannot be satisfied", "", nil},
-		{418, "I'm a teapot", "Cannot brew coffee", "This teapot cannot brew coffee (RFC 2324)", "", nil},
-		{421, "Misdirected Request", "Request misdirected", "Server cannot produce response for this request", "", nil},
-		{422, "Unprocessable Entity", "Validation failed", "Email format is invalid", "", nil},
-		{423, "Locked", "Resource is locked", "Resource is currently being modified by another process", "", nil},
-		{424, "Failed Dependency", "Dependent request failed", "Previous operation in sequence failed", "", nil},
-		{425, "Too Early", "Request sent too early", "Server unwilling to process replayed request", "", nil},
-		{426, "Upgrade Required", "Protocol upgrade required", "Client must upgrade to secure protocol", "", nil},
-		{428, "Precondition Required", "Precondition header required", "Request must include If-Match header", "", nil},
-		{429, "Too Many Requests", "Rate limit exceeded", "Maximum 100 requests per minute exceeded", "", nil},
-		{431, "Request Header Fields Too Large", "Headers too large", "Request headers exceed maximum size limit", "", nil},
-		{451, "Unavailable For Legal Reasons", "Content blocked", "Content unavailable due to legal restrictions", "", nil},
-
-		// 5xx Server error responses
-		{500, "Internal Server Error", "Something went wrong on our end", "Unexpected server error occurred", "", nil},
-		{500, "Internal Server Error", "Database connection failed", "Unable to connect to primary database", "", nil},
-		{501, "Not Implemented", "Feature not implemented", "This functionality is not yet available", "", nil},
-		{502, "Bad Gateway", "Upstream service unavailable", "Authentication service is not responding", "", nil},
-		{502, "Bad Gateway", "Invalid response from upstream", "Received malformed response from backend service", "", nil},
-		{503, "Service Unavailable", "Service temporarily unavailable", "Server is temporarily overloaded", "", nil},
-		{503, "Service Unavailable", "Maintenance mode", "Service under scheduled maintenance", "", nil},
-		{504, "Gateway Timeout", "Request timeout", "Upstream server did not respond within timeout", "", nil},
-		{505, "HTTP Version Not Supported", "HTTP version not supported", "Server does not support HTTP/2.0 protocol", "", nil},
-		{506, "Variant Also Negotiates", "Content negotiation error", "Server configuration error in content negotiation", "", nil},
-		{507, "Insufficient Storage", "Server storage full", "Unable to store representation needed for request", "", nil},
-		{508, "Loop Detected", "Infinite loop detected", "Server detected infinite loop while processing request", "", nil},
-		{510, "Not Extended", "Further extensions required", "Policy for accessing resource has not been met", "", nil},
-		{511, "Network Authentication Required", "Network authentication required", "Client needs to authenticate to gain network access", "", nil},
-	}
-
-	// Create weighted scenarios for realistic distribution
-	var weightedScenarios []Scenario
-
-	// Success responses (60% - 12 entries)
-	successScenarios := make([]Scenario, 0)
-	for _, s := range scenarios {
-		if s.Status >= 200 && s.Status < 300 {
-			successScenarios = append(successScenarios, s)
-		}
-	}
-	for i := 0; i < 12; i++ {
-		weightedScenarios = append(weightedScenarios, successScenarios[rand.Intn(len(successScenarios))])
-	}
+	echoedHeaders := echoHeaders(r)
 
-	// Redirection responses (5% - 1 entry)
-	redirectScenarios := make([]Scenario, 0)
-	for _, s := range scenarios {
-		if s.Status >= 300 && s.Status < 400 {
-			redirectScenarios = append(redirectScenarios, s)
-		}
-	}
-	if len(redirectScenarios) > 0 {
-		weightedScenarios = append(weightedScenarios, redirectScenarios[rand.Intn(len(redirectScenarios))])
-	}
+	// Force a specific scenario when requested via ?status= or ?scenario=, checked before
+	// any random pick so forced requests don't consume draws from a seeded rng
+	forced, err := requestedScenario(r)
+	if err != nil {
+		slog.Warn("Invalid scenario selection", "error", err, "request_id", requestID)
 
-	// Client error responses (25% - 5 entries)
-	clientErrorScenarios := make([]Scenario, 0)
-	for _, s := range scenarios {
-		if s.Status >= 400 && s.Status < 500 {
-			clientErrorScenarios = append(clientErrorScenarios, s)
-		}
-	}
-	for i := 0; i < 5; i++ {
-		weightedScenarios = append(weightedScenarios, clientErrorScenarios[rand.Intn(len(clientErrorScenarios))])
+		response := newErrorResponse(http.StatusBadRequest, "Bad Request", "Invalid scenario selection", err.Error(), requestID)
+		response.EchoedHeaders = echoedHeaders
+		writeJSON(w, http.StatusBadRequest, response)
+		return
 	}
 
-	// Server error responses (10% - 2 entries)
-	serverErrorScenarios := make([]Scenario, 0)
-	for _, s := range scenarios {
-		if s.Status >= 500 && s.Status < 600 {
-			serverErrorScenarios = append(serverErrorScenarios, s)
+	// Otherwise select a random scenario. Chaos mode replaces the pick with a server
+	// error at the configured rate.
+	var randomScenario Scenario
+	injected := false
+	if forced != nil {
+		randomScenario = *forced
+	} else {
+		randomScenario = selectScenario(activeWeights(), rng)
+		if cfg.ErrorInjectionRate > 0 && rng.Float64() < cfg.ErrorInjectionRate {
+			randomScenario = serverErrorScenarios[rng.Intn(len(serverErrorScenarios))]
+			injected = true
 		}
 	}
-	for i := 0; i < 2; i++ {
-		weightedScenarios = append(weightedScenarios, serverErrorScenarios[rand.Intn(len(serverErrorScenarios))])
-	}
 
-	// Select random scenario
-	randomScenario := weightedScenarios[rand.Intn(len(weightedScenarios))]
-	requestID := generateRequestID()
-	timestamp := time.Now().Format(time.RFC3339)
+	// Artificial delay shaped by the chosen status, cut short if the client goes away
+	delay := requestedDelay(r, randomScenario.Status)
+	sleepStart := time.Now()
+	if !sleepContext(r.Context(), time.Duration(delay)*time.Millisecond) {
+		slog.Warn("API request canceled by client",
+			"status", statusClientClosedRequest,
+			"outcome", "canceled",
+			"delay_ms", delay,
+			"elapsed", time.Since(start).String(),
+			"request_id", requestID,
+		)
+		return
+	}
+	actualDelay := time.Since(sleepStart)
 
 	// Record business timing
 	duration := time.Since(start)
@@ -293,117 +376,378 @@ func apiHandler(w http.ResponseWriter, r *http.Request) {
 	}()
 
 	// Simple logging with business context
-	logMessage := fmt.Sprintf("API request completed - Status: %d, Type: %s, Delay: %dms, Duration: %s, RequestID: %s",
-		randomScenario.Status, scenarioType, delay, duration.String(), requestID)
-
+	logLevel := slog.LevelInfo
 	if randomScenario.Status >= 500 {
-		log.Printf("ERROR: %s", logMessage)
+		logLevel = slog.LevelError
 	} else if randomScenario.Status >= 400 {
-		log.Printf("WARN: %s", logMessage)
-	} else {
-		log.Printf("INFO: %s", logMessage)
+		logLevel = slog.LevelWarn
 	}
 
-	// Build and send response
-	w.Header().Set("Content-Type", "application/json")
-
-	// Set location header for redirect responses
+	slog.Log(r.Context(), logLevel, "API request completed",
+		"status", randomScenario.Status,
+		"class", statusClass(randomScenario.Status),
+		"type", scenarioType,
+		"delay_ms", delay,
+		"actual_delay", actualDelay.String(),
+		"injected", injected,
+		"outcome", "completed",
+		"duration", duration.String(),
+		"request_id", requestID,
+	)
+
+	// Set location header for redirect responses, pointing inside the base path
 	if randomScenario.Location != "" {
+		randomScenario.Location = cfg.BasePath + randomScenario.Location
 		w.Header().Set("Location", randomScenario.Location)
 	}
 
-	w.WriteHeader(randomScenario.Status)
+	randomScenario.Details = randomScenario.detailsFor(rng)
+
+	// Tell clients when to retry rate-limited and overloaded responses (1-30 seconds)
+	if randomScenario.Status == http.StatusTooManyRequests || randomScenario.Status == http.StatusServiceUnavailable {
+		retryAfter := rng.Intn(30) + 1
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		randomScenario.Details = fmt.Sprintf("%s, retry after %d seconds", randomScenario.Details, retryAfter)
+	}
 
 	// Build response based on scenario type
 	if randomScenario.Status >= 400 {
 		// Error response
-		response := ErrorResponse{
-			Status:    randomScenario.Status,
-			Error:     randomScenario.Error,
-			Message:   randomScenario.Message,
-			Details:   randomScenario.Details,
-			Location:  randomScenario.Location,
-			RequestID: requestID,
-			Timestamp: timestamp,
-		}
-		json.NewEncoder(w).Encode(response)
+		response := newErrorResponse(randomScenario.Status, randomScenario.Error, randomScenario.Message, randomScenario.Details, requestID)
+		response.Location = randomScenario.Location
+		response.EchoedHeaders = echoedHeaders
+		writeJSON(w, randomScenario.Status, response)
 	} else {
 		// Success or redirect response
+		var data interface{}
+		if randomScenario.Data != nil {
+			data = randomScenario.Data(delay)
+		}
+
 		response := SuccessResponse{
-			Status:    randomScenario.Status,
-			Message:   randomScenario.Message,
-			Data:      randomScenario.Data,
+			Status:        randomScenario.Status,
+			Message:       randomScenario.Message,
+			Data:          data,
+			EchoedHeaders: echoedHeaders,
+			RequestID:     requestID,
+			Timestamp:     time.Now().Format(time.RFC3339),
+		}
+		writeJSON(w, randomScenario.Status, response)
+	}
+}
+
+// Slow handler - always sleeps close to the configured max delay
+func slowHandler(w http.ResponseWriter, r *http.Request) {
+	// Top 10% of the configured range
+	spread := (cfg.MaxDelayMs - cfg.MinDelayMs) / 10
+	fixedLatencyResponse(w, r, cfg.MaxDelayMs-rng.Intn(spread+1), "Slow response")
+}
+
+// Fast handler - always responds with near-zero delay
+func fastHandler(w http.ResponseWriter, r *http.Request) {
+	fixedLatencyResponse(w, r, rng.Intn(6), "Fast response")
+}
+
+// Sleep for delay ms and reply with a success response reporting the measured delay
+func fixedLatencyResponse(w http.ResponseWriter, r *http.Request, delay int, message string) {
+	requestID := requestIDFromContext(r.Context())
+
+	start := time.Now()
+	if !sleepContext(r.Context(), time.Duration(delay)*time.Millisecond) {
+		slog.Warn("Fixed latency request canceled by client",
+			"path", r.URL.Path, "status", statusClientClosedRequest, "delay_ms", delay, "request_id", requestID)
+		return
+	}
+	measured := time.Since(start)
+
+	slog.Info("Fixed latency request completed",
+		"path", r.URL.Path, "delay_ms", delay, "measured", measured.String(), "request_id", requestID)
+
+	writeJSON(w, http.StatusOK, SuccessResponse{
+		Status:  http.StatusOK,
+		Message: message,
+		Data: map[string]interface{}{
+			"delay":         fmt.Sprintf("%dms", delay),
+			"measuredDelay": measured.String(),
+		},
+		RequestID: requestID,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// Echo handler - returns the posted JSON body
+func echoHandler(w http.ResponseWriter, r *http.Request) {
+	requestID := requestIDFromContext(r.Context())
+	timestamp := time.Now().Format(time.RFC3339)
+
+	// Only JSON bodies can be echoed, parameters such as charset are fine
+	contentType := r.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err != nil || mediaType != mediaTypeJSON {
+		slog.Warn("Unsupported echo content type", "content_type", contentType, "request_id", requestID)
+
+		writeError(w, http.StatusUnsupportedMediaType, "Unsupported Media Type", "Media type not supported",
+			fmt.Sprintf("Content-Type must be %s", mediaTypeJSON), requestID)
+		return
+	}
+
+	var payload interface{}
+	size, ok := decodeJSONBody(w, r, &payload, "Malformed JSON in request body")
+	if !ok {
+		return
+	}
+
+	slog.Info("Echo request completed", "size_bytes", size, "request_id", requestID)
+
+	writeJSON(w, http.StatusOK, SuccessResponse{
+		Status:    http.StatusOK,
+		Message:   "Request body echoed",
+		Data:      payload,
+		RequestID: requestID,
+		Timestamp: timestamp,
+	})
+}
+
+// Stream handler - writes one JSON line per chunk, flushing after each
+func streamHandler(w http.ResponseWriter, r *http.Request) {
+	requestID := requestIDFromContext(r.Context())
+
+	chunks := 5
+	if value := r.URL.Query().Get("chunks"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxStreamChunks {
+			slog.Warn("Invalid stream chunks parameter", "chunks", value, "request_id", requestID)
+
+			writeError(w, http.StatusBadRequest, "Bad Request", "Invalid request parameters", fmt.Sprintf("chunks must be a number between 1 and %d", maxStreamChunks), requestID)
+			return
+		}
+		chunks = n
+	}
+
+	// NDJSON by default, "key: value" blocks separated by a blank line for text clients
+	asText := mediaTypeFromContext(r.Context()) == mediaTypeText
+	if asText {
+		w.Header().Set("Content-Type", mediaTypeText+"; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", mediaTypeNDJSON)
+	}
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+
+	for i := 1; i <= chunks; i++ {
+		line, _ := json.Marshal(StreamChunk{
+			Chunk:     i,
+			Total:     chunks,
 			RequestID: requestID,
-			Timestamp: timestamp,
+			Timestamp: time.Now().Format(time.RFC3339),
+		})
+		if asText {
+			line, _ = jsonToText(line)
+		}
+		line = append(line, '\n')
+		if _, err := w.Write(line); err != nil {
+			slog.Warn("Stream write failed", "chunk", i, "chunks", chunks, "error", err, "request_id", requestID)
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			slog.Warn("Stream flush failed", "chunk", i, "chunks", chunks, "error", err, "request_id", requestID)
+			return
+		}
+
+		if i == chunks {
+			break
+		}
+
+		// Stop early when the client goes away
+		if !sleepContext(r.Context(), streamChunkDelay) {
+			slog.Warn("Stream canceled by client", "chunks_sent", i, "chunks", chunks, "request_id", requestID)
+			return
 		}
-		json.NewEncoder(w).Encode(response)
 	}
+
+	slog.Info("Stream completed", "chunks", chunks, "request_id", requestID)
 }
 
 // 404 handler - simplified
 func notFoundHandler(w http.ResponseWriter, r *http.Request) {
-	log.Printf("WARN: Route not found - Path: %s, Method: %s", r.URL.Path, r.Method)
+	requestID := requestIDFromContext(r.Context())
+	slog.Warn("Route not found", "path", r.URL.Path, "method", r.Method, "request_id", requestID)
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusNotFound)
+	writeError(w, http.StatusNotFound, "Not Found", fmt.Sprintf("Route %s not found", r.URL.Path), "", requestID)
+}
 
-	response := NotFoundResponse{
-		Error:     "Not Found",
-		Message:   fmt.Sprintf("Route %s not found", r.URL.Path),
-		Timestamp: time.Now().Format(time.RFC3339),
-	}
+// 405 handler - known route, wrong method
+func methodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	requestID := requestIDFromContext(r.Context())
+	slog.Warn("Method not allowed", "path", r.URL.Path, "method", r.Method, "request_id", requestID)
 
-	json.NewEncoder(w).Encode(response)
+	writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed", fmt.Sprintf("Method %s not allowed on %s", r.Method, r.URL.Path), "", requestID)
 }
 
 func main() {
-	// Get port from environment or default to 8080
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	// Log in the LOG_FORMAT and LOG_LEVEL from the environment while the config loads,
+	// so warnings about it come out in the same format as everything after
+	slog.SetDefault(newStartupLogger())
+
+	// Load configuration from CONFIG_FILE if set, environment variables override file values
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		fileCfg, err := LoadConfigFile(path)
+		if err != nil {
+			slog.Error("Failed to load config file", "path", path, "error", err)
+			os.Exit(1)
+		}
+		cfg = fileCfg
+	} else {
+		cfg = loadConfig()
 	}
+	port := cfg.Port
+
+	// Structured logger for all application logs, now with the full config applied
+	slog.SetDefault(newLogger(cfg.LogFormat, cfg.LogLevel))
+
+	// Seed random generator so a run can be reproduced with RANDOM_SEED
+	rng = newRand(cfg.RandomSeed)
+	slog.Info("Configuration loaded", "config", cfg)
+
+	// Start with the configured profile, POST /admin/profile can swap it later
+	setActiveProfile(cfg.ScenarioProfile, cfg.ScenarioWeights)
 
 	// Create router
 	r := mux.NewRouter()
 
+	// Mount routes under BASE_PATH when running behind a proxy on a subpath. Routes go on
+	// the root router with the prefix prepended rather than on a PathPrefix subrouter,
+	// because mux turns a method mismatch inside a subrouter into a 404 instead of a 405.
+	route := func(path string, handler http.Handler, method string) {
+		r.Handle(cfg.BasePath+path, handler).Methods(method)
+	}
+	if cfg.BasePath != "" {
+		route("", http.HandlerFunc(rootHandler), "GET")
+	}
+
 	// Define routes
-	r.HandleFunc("/", rootHandler).Methods("GET")
-	r.HandleFunc("/api", apiHandler).Methods("GET")
-	r.HandleFunc("/health", healthHandler).Methods("GET")
+	route("/", http.HandlerFunc(rootHandler), "GET")
+	route("/api", http.HandlerFunc(apiHandler), "GET")
+	route("/health", http.HandlerFunc(healthHandler), "GET")
+	route("/livez", http.HandlerFunc(livezHandler), "GET")
+	route("/readyz", http.HandlerFunc(readyzHandler), "GET")
+	route("/startupz", http.HandlerFunc(startupzHandler), "GET")
+	route("/version", http.HandlerFunc(versionHandler), "GET")
+	route("/api/users", http.HandlerFunc(usersHandler), "GET")
+	route("/api/search", http.HandlerFunc(searchHandler), "GET")
+	route("/api/batch", http.HandlerFunc(batchHandler), "GET")
+	route("/api/echo", http.HandlerFunc(echoHandler), "POST")
+	route("/api/stream", http.HandlerFunc(streamHandler), "GET")
+	route("/api/slow", http.HandlerFunc(slowHandler), "GET")
+	route("/api/fast", http.HandlerFunc(fastHandler), "GET")
+	if cfg.AdminToken != "" {
+		route("/admin/profile", adminAuthMiddleware(http.HandlerFunc(adminProfileHandler)), "POST")
+		route("/admin/log", adminAuthMiddleware(http.HandlerFunc(adminLogHandler)), "POST")
+	}
 
-	// 404 handler for undefined routes
+	// 404 handler for undefined routes, 405 for known routes hit with the wrong method
 	r.NotFoundHandler = http.HandlerFunc(notFoundHandler)
+	r.MethodNotAllowedHandler = http.HandlerFunc(methodNotAllowedHandler)
+
+	// Middleware, in the order documented on buildMiddlewareChain
+	handler := applyMiddleware(r, buildMiddlewareChain(cfg))
 
 	// Start server
+	scheme := "http"
+	if cfg.TLSCertFile != "" {
+		scheme = "https"
+	}
+	baseURL := fmt.Sprintf("%s://localhost:%s%s", scheme, port, cfg.BasePath)
 	fmt.Printf("🚀 Go server is running on port %s\n", port)
-	fmt.Printf("📍 Root endpoint: http://localhost:%s/\n", port)
-	fmt.Printf("🎲 API endpoint: http://localhost:%s/api\n", port)
-	fmt.Printf("❤️  Health check: http://localhost:%s/health\n", port)
+	fmt.Printf("📍 Root endpoint: %s/\n", baseURL)
+	fmt.Printf("🎲 API endpoint: %s/api\n", baseURL)
+	fmt.Printf("👥 Users endpoint: %s/api/users?page=1&limit=10\n", baseURL)
+	fmt.Printf("🔍 Search endpoint: %s/api/search?q=product&minPrice=10&maxPrice=50\n", baseURL)
+	fmt.Printf("📦 Batch endpoint: %s/api/batch?n=3\n", baseURL)
+	fmt.Printf("🔁 Echo endpoint: POST %s/api/echo\n", baseURL)
+	fmt.Printf("🌊 Stream endpoint: %s/api/stream\n", baseURL)
+	fmt.Printf("🐢 Slow endpoint: %s/api/slow\n", baseURL)
+	fmt.Printf("🐇 Fast endpoint: %s/api/fast\n", baseURL)
+	fmt.Printf("❤️  Health check: %s/health\n", baseURL)
+	fmt.Printf("🩺 Probes: %s/livez, /readyz, /startupz\n", baseURL)
+	fmt.Printf("🏷️  Version: %s/version\n", baseURL)
+	if cfg.AdminToken != "" {
+		fmt.Printf("🎛️  Admin profile: POST %s/admin/profile\n", baseURL)
+		fmt.Printf("📝 Admin log: POST %s/admin/log\n", baseURL)
+	}
 
 	// Graceful shutdown
 	server := &http.Server{
-		Addr:    ":" + port,
-		Handler: r,
+		Addr:              ":" + port,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
 	}
 
 	// Channel to listen for interrupt signal
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
+	// Load the certificate up front so a bad or half-set path fails before the probes report ready
+	if err := cfg.validateTLS(); err != nil {
+		slog.Error("Invalid TLS configuration", "error", err)
+		os.Exit(1)
+	}
+	if cfg.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			slog.Error("Failed to load TLS certificate", "cert_file", cfg.TLSCertFile, "key_file", cfg.TLSKeyFile, "error", err)
+			os.Exit(1)
+		}
+		server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+
+	// Bind before serving so the startup and readiness probes only pass once the port is open
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		slog.Error("Failed to listen", "addr", server.Addr, "error", err)
+		os.Exit(1)
+	}
+	serverStarted.Store(true)
+	serverReady.Store(true)
+
+	// Serve returns http.ErrServerClosed once Shutdown starts, anything else is a real failure
+	serveErr := make(chan error, 1)
 	go func() {
-		log.Fatal(server.ListenAndServe())
+		if server.TLSConfig != nil {
+			serveErr <- server.ServeTLS(listener, "", "")
+			return
+		}
+		serveErr <- server.Serve(listener)
 	}()
 
-	// Wait for interrupt signal
-	<-sigChan
+	// Wait for interrupt signal, or exit if the server stops on its own
+	select {
+	case err := <-serveErr:
+		slog.Error("Server stopped unexpectedly", "error", err)
+		os.Exit(1)
+	case <-sigChan:
+	}
+
+	// Fail readiness first and keep accepting requests while load balancers catch up
+	serverReady.Store(false)
 	fmt.Println("\n🛑 Shutting down server...")
+	if cfg.ShutdownDelay > 0 {
+		slog.Info("Waiting before closing the listener", "shutdown_delay", cfg.ShutdownDelay.String())
+		time.Sleep(cfg.ShutdownDelay)
+	}
 
-	// Shutdown with timeout
+	// Shutdown with timeout, waiting for in-flight requests to finish
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	if err := server.Shutdown(shutdownCtx); err != nil {
-		log.Printf("Server shutdown error: %v", err)
+		slog.Error("Server shutdown error", "error", err)
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("Server stopped with error", "error", err)
 	}
 
 	fmt.Println("✅ Server gracefully stopped")
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/time/rate"
+)
+
+// Assemble the middleware stack, outermost first. Everything wraps the router
+// rather than going through r.Use, because mux only runs Use middleware on
+// matched routes and 404s and CORS preflights need the same treatment.
+//
+//  1. requestID is outermost so every later step, including recovery, logs and
+//     replies with the same ID.
+//  2. The access log sits just inside it, so every response gets one line with
+//     its final status, including 404s, 405s, 429s and limit rejections.
+//  3. Content negotiation wraps every step that writes a body, so recovery 500s,
+//     413/414/431 limits, 429s and 404s come back as text too when asked for.
+//  4. recovery comes next so a panic anywhere below still gets a 500 body.
+//  5. URI, header and body limits reject oversized requests before any other work.
+//  6. CORS answers preflights and adds headers before rate limiting, so browsers
+//     can read 429 responses.
+//  7. The rate limiter sits right in front of the router.
+func buildMiddlewareChain(cfg *Config) []mux.MiddlewareFunc {
+	chain := []mux.MiddlewareFunc{
+		requestIDMiddleware,
+		accessLogMiddleware,
+		contentNegotiationMiddleware(cfg.BasePath),
+		recoveryMiddleware,
+		requestLimitsMiddleware(cfg.MaxURILength, cfg.MaxHeaderBytes),
+		bodyLimitMiddleware(cfg.MaxBodyBytes),
+	}
+	if len(cfg.CORSAllowedOrigins) > 0 {
+		chain = append(chain, corsMiddleware(cfg.CORSAllowedOrigins, cfg.BasePath))
+	}
+	if cfg.RateLimitRPS > 0 {
+		chain = append(chain, newRateLimiter(cfg.RateLimitRPS, cfg.RateLimitBurst, cfg.BasePath).middleware)
+	}
+	return chain
+}
+
+// Wrap h in the chain so chain[0] runs first
+func applyMiddleware(h http.Handler, chain []mux.MiddlewareFunc) http.Handler {
+	for i := len(chain) - 1; i >= 0; i-- {
+		h = chain[i](h)
+	}
+	return h
+}
+
+type contextKey string
+
+const requestIDKey contextKey = "requestID"
+
+// Request ID for the current request, set by requestIDMiddleware
+func requestIDFromContext(ctx context.Context) string {
+	if requestID, ok := ctx.Value(requestIDKey).(string); ok {
+		return requestID
+	}
+	return generateRequestID()
+}
+
+// Reuse a well-formed incoming X-Request-ID or generate one, and echo it on the response
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-ID")
+		if !validRequestID(requestID) {
+			requestID = generateRequestID()
+		}
+
+		w.Header().Set("X-Request-ID", requestID)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, requestID)))
+	})
+}
+
+// statusRecorder remembers the status a handler wrote for the access log
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	if sr.status == 0 {
+		sr.status = status
+	}
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+func (sr *statusRecorder) Write(b []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+	return sr.ResponseWriter.Write(b)
+}
+
+// Let http.ResponseController reach the underlying writer for flushing
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
+// Log one line per request with its method, path, final status and duration
+func accessLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		status := recorder.status
+		if status == 0 {
+			// Nothing written: the client went away mid-request, or the handler sent an empty 200
+			status = http.StatusOK
+			if r.Context().Err() != nil {
+				status = statusClientClosedRequest
+			}
+		}
+
+		slog.Info("Request handled",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", status,
+			"duration_ms", time.Since(start).Milliseconds(),
+			"request_id", requestIDFromContext(r.Context()),
+			ipAttr(clientIP(r)),
+		)
+	})
+}
+
+// Accept caller-provided IDs only if they are short and header-safe
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Recover from handler panics and reply with a 500 instead of dropping the connection
+func recoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			// Let the server abort the response as it would without this middleware
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+
+			requestID := requestIDFromContext(r.Context())
+			slog.Error("Panic recovered",
+				"path", r.URL.Path, "method", r.Method, "error", err,
+				"request_id", requestID, "stack", string(debug.Stack()))
+
+			writeError(w, http.StatusInternalServerError, "Internal Server Error", "Something went wrong on our end", "Unexpected server error occurred", requestID)
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimiter hands out a token bucket per client IP
+type rateLimiter struct {
+	mu        sync.Mutex
+	clients   map[string]*clientLimiter
+	rps       rate.Limit
+	burst     int
+	lastSweep time.Time
+	// Probes under this prefix are never limited
+	basePath string
+}
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Buckets idle for this long are dropped so the map doesn't grow forever
+const rateLimiterIdleTTL = 3 * time.Minute
+
+func newRateLimiter(rps float64, burst int, basePath string) *rateLimiter {
+	return &rateLimiter{
+		clients:   make(map[string]*clientLimiter),
+		rps:       rate.Limit(rps),
+		burst:     burst,
+		lastSweep: time.Now(),
+		basePath:  basePath,
+	}
+}
+
+// Report whether the client may make another request right now
+func (rl *rateLimiter) allow(ip string) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(rl.lastSweep) > rateLimiterIdleTTL {
+		for key, c := range rl.clients {
+			if now.Sub(c.lastSeen) > rateLimiterIdleTTL {
+				delete(rl.clients, key)
+			}
+		}
+		rl.lastSweep = now
+	}
+
+	c, ok := rl.clients[ip]
+	if !ok {
+		c = &clientLimiter{limiter: rate.NewLimiter(rl.rps, rl.burst)}
+		rl.clients[ip] = c
+	}
+	c.lastSeen = now
+
+	return c.limiter.Allow()
+}
+
+// Reject requests over the per-client rate with the 429 response shape. Probes are
+// exempt, a kubelet polling from one IP would otherwise fail them and restart the pod.
+func (rl *rateLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := clientIP(r)
+		if probePaths[strings.TrimPrefix(r.URL.Path, rl.basePath)] || rl.allow(ip) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		requestID := requestIDFromContext(r.Context())
+		slog.Warn("Rate limit exceeded", "path", r.URL.Path, "method", r.Method, ipAttr(ip), "request_id", requestID)
+
+		writeError(w, http.StatusTooManyRequests, "Too Many Requests", "Rate limit exceeded", fmt.Sprintf("Maximum %g requests per second exceeded", float64(rl.rps)), requestID)
+	})
+}
+
+// Reject requests whose URI or headers exceed the configured limits with real 414/431 responses
+func requestLimitsMiddleware(maxURILength, maxHeaderBytes int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := requestIDFromContext(r.Context())
+
+			if len(r.RequestURI) > maxURILength {
+				slog.Warn("Request URI too long", "length", len(r.RequestURI), "limit", maxURILength, "request_id", requestID)
+
+				writeError(w, http.StatusRequestURITooLong, "URI Too Long", "Request URI too long", fmt.Sprintf("URL exceeds maximum length of %d characters", maxURILength), requestID)
+				return
+			}
+
+			// Approximate wire size of the header block ("Name: value\r\n" per value)
+			headerBytes := 0
+			for name, values := range r.Header {
+				for _, value := range values {
+					headerBytes += len(name) + len(value) + 4
+				}
+			}
+			if headerBytes > maxHeaderBytes {
+				slog.Warn("Request headers too large", "size_bytes", headerBytes, "limit", maxHeaderBytes, "request_id", requestID)
+
+				writeError(w, http.StatusRequestHeaderFieldsTooLarge, "Request Header Fields Too Large", "Headers too large", fmt.Sprintf("Request headers exceed maximum size limit of %d bytes", maxHeaderBytes), requestID)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Cap request bodies at maxBytes for every route. Bodies with a declared length
+// over the limit get a 413 straight away, others are wrapped in MaxBytesReader so
+// reading past the limit fails with *http.MaxBytesError, which decodeJSONBody
+// answers with the same 413.
+func bodyLimitMiddleware(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.ContentLength > maxBytes {
+				requestID := requestIDFromContext(r.Context())
+				slog.Warn("Request body too large", "path", r.URL.Path, "size_bytes", r.ContentLength, "limit_bytes", maxBytes, "request_id", requestID)
+				writePayloadTooLarge(w, requestID, maxBytes)
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Reply with the 413 response shape for a body over the limit
+func writePayloadTooLarge(w http.ResponseWriter, requestID string, limit int64) {
+	writeError(w, http.StatusRequestEntityTooLarge, "Payload Too Large", "Request entity too large", fmt.Sprintf("Request body exceeds %d byte limit", limit), requestID)
+}
+
+// Health and probe endpoints, which get no CORS headers
+var probePaths = map[string]bool{"/health": true, "/livez": true, "/readyz": true, "/startupz": true}
+
+// Headers browsers may send on cross-origin requests, including trace context
+const corsAllowedHeaders = "Content-Type, Authorization, X-Request-ID, traceparent, tracestate, baggage"
+
+// Add CORS headers for allowed origins and answer preflight requests
+func corsMiddleware(origins []string, basePath string) func(http.Handler) http.Handler {
+	allowAll := false
+	allowed := make(map[string]bool)
+	for _, origin := range origins {
+		if origin == "*" {
+			allowAll = true
+		}
+		allowed[origin] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+
+			// Probe endpoints are not meant for browsers
+			if origin == "" || probePaths[strings.TrimPrefix(r.URL.Path, basePath)] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add("Vary", "Origin")
+			if !allowAll && !allowed[origin] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if allowAll {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			w.Header().Set("Access-Control-Expose-Headers", "Location, X-Request-ID")
+
+			// Preflight requests are answered here without reaching the router
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+				w.Header().Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
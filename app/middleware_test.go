@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func TestRateLimiterSkipsProbes(t *testing.T) {
+	handler := newRateLimiter(1, 2, "/demo").middleware(okHandler)
+
+	for _, path := range []string{"/demo/health", "/demo/livez", "/demo/readyz", "/demo/startupz"} {
+		for i := 0; i < 4; i++ {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("call %d to %s got %d, want probes never rate limited", i+1, path, rec.Code)
+			}
+		}
+	}
+
+	codes := make([]int, 3)
+	for i := range codes {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/demo/api", nil))
+		codes[i] = rec.Code
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Errorf("/demo/api got %v, want the burst of 2 then 429", codes)
+	}
+}
+
+func TestBodyLimitChunkedOversizeOnAdminLog(t *testing.T) {
+	// Valid JSON well past the limit, sent without a Content-Length like a chunked upload
+	body := io.MultiReader(
+		strings.NewReader(`{"message":"`),
+		strings.NewReader(strings.Repeat("a", 4096)),
+		strings.NewReader(`"}`),
+	)
+	req := httptest.NewRequest(http.MethodPost, "/admin/log", body)
+	req.ContentLength = -1
+
+	rec := httptest.NewRecorder()
+	bodyLimitMiddleware(1024)(http.HandlerFunc(adminLogHandler)).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("got %d, want 413 for a chunked body over the limit", rec.Code)
+	}
+	var response ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || response.Error != "Payload Too Large" {
+		t.Errorf("got body %q, want the 413 error response", rec.Body.String())
+	}
+}
+
+func TestMiddlewareChainOrder(t *testing.T) {
+	c := defaultConfig()
+	c.CORSAllowedOrigins = []string{"https://example.com"}
+	c.RateLimitRPS, c.RateLimitBurst = 1, 1
+	c.MaxURILength = 32
+	c.MaxBodyBytes = 8
+
+	handler := applyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/panic" {
+			panic("boom")
+		}
+		w.WriteHeader(http.StatusOK)
+	}), buildMiddlewareChain(c))
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("request ID on a recovered 500", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		rec := serve(req)
+
+		var response ErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("decoding 500 body %q: %v", rec.Body.String(), err)
+		}
+		if rec.Code != http.StatusInternalServerError || response.RequestID == "" || response.RequestID != rec.Header().Get("X-Request-ID") {
+			t.Errorf("got %d with body ID %q and header ID %q, want a 500 carrying the request ID",
+				rec.Code, response.RequestID, rec.Header().Get("X-Request-ID"))
+		}
+	})
+
+	t.Run("CORS headers on a 429", func(t *testing.T) {
+		var rec *httptest.ResponseRecorder
+		for i := 0; i < 2; i++ {
+			req := httptest.NewRequest(http.MethodGet, "/api", nil)
+			req.RemoteAddr = "192.0.2.2:1234"
+			req.Header.Set("Origin", "https://example.com")
+			rec = serve(req)
+		}
+		if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Access-Control-Allow-Origin") != "https://example.com" {
+			t.Errorf("got %d with Access-Control-Allow-Origin %q, want a 429 readable by the origin",
+				rec.Code, rec.Header().Get("Access-Control-Allow-Origin"))
+		}
+	})
+
+	t.Run("limit responses rendered as text", func(t *testing.T) {
+		longURI := httptest.NewRequest(http.MethodGet, "/api?q="+strings.Repeat("a", 64), nil)
+		largeBody := httptest.NewRequest(http.MethodPost, "/api/echo", strings.NewReader(`{"message":"too long"}`))
+
+		for want, req := range map[int]*http.Request{http.StatusRequestURITooLong: longURI, http.StatusRequestEntityTooLarge: largeBody} {
+			req.RemoteAddr = "192.0.2.3:1234"
+			req.Header.Set("Accept", "text/plain")
+			rec := serve(req)
+			if rec.Code != want || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+				t.Errorf("%s got %d as %q, want %d as text/plain", req.URL.Path, rec.Code, rec.Header().Get("Content-Type"), want)
+			}
+		}
+	})
+}
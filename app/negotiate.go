@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Response media types the API can produce, in order of preference on a tie
+const (
+	mediaTypeJSON   = "application/json"
+	mediaTypeText   = "text/plain"
+	mediaTypeNDJSON = "application/x-ndjson"
+)
+
+// Routes that answer in their own media type instead of JSON, keyed by path below
+// the base path. They offer that type and plain text.
+var routeMediaTypes = map[string]string{"/api/stream": mediaTypeNDJSON}
+
+// Pick the response type for an Accept header from offers, "" when the client accepts
+// none of them. A missing header means anything goes, so the first offer is the default.
+func negotiateContentType(accept string, offers []string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := acceptQuality(accept, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// Quality the Accept header gives mediaType, taken from its most specific matching range
+func acceptQuality(accept, mediaType string) float64 {
+	mainType, _, _ := strings.Cut(mediaType, "/")
+	q, specificity := 0.0, -1
+
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaRange := strings.ToLower(strings.TrimSpace(params[0]))
+
+		rangeSpecificity := -1
+		switch mediaRange {
+		case mediaType:
+			rangeSpecificity = 2
+		case mainType + "/*":
+			rangeSpecificity = 1
+		case "*/*":
+			rangeSpecificity = 0
+		}
+		if rangeSpecificity <= specificity {
+			continue
+		}
+
+		// A malformed or out-of-range q is ignored, leaving the default of 1
+		rangeQ := 1.0
+		for _, param := range params[1:] {
+			key, value, _ := strings.Cut(param, "=")
+			if strings.EqualFold(strings.TrimSpace(key), "q") {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && parsed >= 0 && parsed <= 1 {
+					rangeQ = parsed
+				}
+			}
+		}
+		q, specificity = rangeQ, rangeSpecificity
+	}
+	return q
+}
+
+const mediaTypeKey contextKey = "mediaType"
+
+// Response type picked by contentNegotiationMiddleware, JSON when none was negotiated
+func mediaTypeFromContext(ctx context.Context) string {
+	if mediaType, ok := ctx.Value(mediaTypeKey).(string); ok {
+		return mediaType
+	}
+	return mediaTypeJSON
+}
+
+// Answer requests whose Accept header rules out every type the route can produce
+// with a real 406, and render JSON responses as plain text for clients that prefer it.
+// Handlers writing their own media type read the pick with mediaTypeFromContext.
+func contentNegotiationMiddleware(basePath string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			accept := r.Header.Get("Accept")
+			w.Header().Add("Vary", "Accept")
+
+			offers := []string{mediaTypeJSON, mediaTypeText}
+			if routeType, ok := routeMediaTypes[strings.TrimPrefix(r.URL.Path, basePath)]; ok {
+				offers = []string{routeType, mediaTypeText}
+			}
+
+			mediaType := negotiateContentType(accept, offers)
+			if mediaType == "" {
+				requestID := requestIDFromContext(r.Context())
+				slog.Warn("No acceptable content type", "path", r.URL.Path, "accept", accept, "request_id", requestID)
+
+				writeError(w, http.StatusNotAcceptable, "Not Acceptable", "Cannot produce requested content type",
+					"Supported types are "+strings.Join(offers, " and "), requestID)
+				return
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), mediaTypeKey, mediaType))
+			if mediaType == mediaTypeText {
+				next.ServeHTTP(&textResponseWriter{ResponseWriter: w}, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// textResponseWriter turns JSON bodies into "key: value" lines. writeJSON sends
+// each body in one Write, so every Write on a JSON response is a whole document.
+type textResponseWriter struct {
+	http.ResponseWriter
+	convert bool
+}
+
+func (tw *textResponseWriter) WriteHeader(status int) {
+	if strings.HasPrefix(tw.Header().Get("Content-Type"), mediaTypeJSON) {
+		tw.Header().Set("Content-Type", mediaTypeText+"; charset=utf-8")
+		tw.convert = true
+	}
+	tw.ResponseWriter.WriteHeader(status)
+}
+
+func (tw *textResponseWriter) Write(b []byte) (int, error) {
+	if !tw.convert {
+		return tw.ResponseWriter.Write(b)
+	}
+
+	text, err := jsonToText(b)
+	if err != nil {
+		return tw.ResponseWriter.Write(b)
+	}
+	if _, err := tw.ResponseWriter.Write(text); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Let http.ResponseController reach the underlying writer for flushing
+func (tw *textResponseWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+// Render one JSON document as "path: value" lines
+func jsonToText(b []byte) ([]byte, error) {
+	var text bytes.Buffer
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := renderText(&text, dec, ""); err != nil {
+		return nil, err
+	}
+	return text.Bytes(), nil
+}
+
+// Write one JSON value as "path: value" lines, keeping the field order of the body
+func renderText(out io.Writer, dec *json.Decoder, path string) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			child := fmt.Sprint(key)
+			if path != "" {
+				child = path + "." + child
+			}
+			if err := renderText(out, dec, child); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+		return err
+	case json.Delim('['):
+		for i := 0; dec.More(); i++ {
+			if err := renderText(out, dec, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+		return err
+	case nil:
+		_, err = fmt.Fprintf(out, "%s: null\n", path)
+		return err
+	default:
+		_, err = fmt.Fprintf(out, "%s: %v\n", path, token)
+		return err
+	}
+}
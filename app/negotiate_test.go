@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAcceptQuality(t *testing.T) {
+	tests := []struct {
+		name      string
+		accept    string
+		mediaType string
+		want      float64
+	}{
+		{"exact match", "application/json", mediaTypeJSON, 1},
+		{"no match", "image/png", mediaTypeJSON, 0},
+		{"explicit q", "text/plain;q=0.4", mediaTypeText, 0.4},
+		{"q=0 rules a type out", "text/plain;q=0", mediaTypeText, 0},
+		{"full wildcard", "*/*;q=0.3", mediaTypeJSON, 0.3},
+		{"subtype wildcard", "text/*;q=0.6", mediaTypeText, 0.6},
+		{"subtype wildcard other type", "text/*", mediaTypeJSON, 0},
+		{"specific range beats wildcard", "*/*;q=0.9, text/plain;q=0.2", mediaTypeText, 0.2},
+		{"q=0 specific beats wildcard", "text/plain;q=0, */*", mediaTypeText, 0},
+		{"subtype wildcard beats full wildcard", "*/*;q=0.1, text/*;q=0.7", mediaTypeText, 0.7},
+		{"case insensitive type", "TEXT/Plain", mediaTypeText, 1},
+		{"case insensitive q", "text/plain;Q=0.5", mediaTypeText, 0.5},
+		{"spaces around params", "text/plain ; q = 0.5", mediaTypeText, 0.5},
+		{"other params ignored", "text/plain;charset=utf-8;q=0.8", mediaTypeText, 0.8},
+		{"malformed q ignored", "text/plain;q=abc", mediaTypeText, 1},
+		{"out of range q ignored", "text/plain;q=2", mediaTypeText, 1},
+		{"negative q ignored", "text/plain;q=-1", mediaTypeText, 1},
+		{"param without value", "text/plain;q", mediaTypeText, 1},
+		{"empty parts", ",,application/json,", mediaTypeJSON, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := acceptQuality(tt.accept, tt.mediaType); got != tt.want {
+				t.Errorf("acceptQuality(%q, %q) = %g, want %g", tt.accept, tt.mediaType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNegotiateContentType(t *testing.T) {
+	apiOffers := []string{mediaTypeJSON, mediaTypeText}
+	streamOffers := []string{mediaTypeNDJSON, mediaTypeText}
+
+	tests := []struct {
+		name   string
+		accept string
+		offers []string
+		want   string
+	}{
+		{"missing header picks first offer", "", apiOffers, mediaTypeJSON},
+		{"blank header picks first offer", "  ", streamOffers, mediaTypeNDJSON},
+		{"wildcard ties go to first offer", "*/*", apiOffers, mediaTypeJSON},
+		{"text preferred", "text/plain", apiOffers, mediaTypeText},
+		{"higher q wins", "application/json;q=0.5, text/plain", apiOffers, mediaTypeText},
+		{"nothing acceptable", "image/png", apiOffers, ""},
+		{"everything ruled out", "application/json;q=0, text/*;q=0", apiOffers, ""},
+		{"route type accepted", "application/x-ndjson", streamOffers, mediaTypeNDJSON},
+		{"text on a route with its own type", "text/plain", streamOffers, mediaTypeText},
+		{"json is not ndjson", "application/json", streamOffers, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := negotiateContentType(tt.accept, tt.offers); got != tt.want {
+				t.Errorf("negotiateContentType(%q, %v) = %q, want %q", tt.accept, tt.offers, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamHonoursNegotiatedType(t *testing.T) {
+	handler := contentNegotiationMiddleware("")(http.HandlerFunc(streamHandler))
+
+	tests := []struct {
+		accept      string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"application/x-ndjson", http.StatusOK, mediaTypeNDJSON, `{"chunk":1,"total":1,`},
+		{"text/plain", http.StatusOK, mediaTypeText, "chunk: 1\ntotal: 1\n"},
+		{"image/png", http.StatusNotAcceptable, mediaTypeJSON, `"status":406`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/stream?chunks=1", nil)
+			req.Header.Set("Accept", tt.accept)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status || !strings.HasPrefix(rec.Header().Get("Content-Type"), tt.contentType) {
+				t.Errorf("got %d as %q, want %d as %s", rec.Code, rec.Header().Get("Content-Type"), tt.status, tt.contentType)
+			}
+			if !strings.Contains(rec.Body.String(), tt.body) {
+				t.Errorf("body %q does not contain %q", rec.Body.String(), tt.body)
+			}
+		})
+	}
+}
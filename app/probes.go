@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// Probe state for Kubernetes. Startup completes once the listener is bound, and
+// readiness drops again when shutdown begins so traffic drains before exit.
+var (
+	serverStarted atomic.Bool
+	serverReady   atomic.Bool
+)
+
+type ProbeResponse struct {
+	Check  string `json:"check"`
+	Status string `json:"status"`
+}
+
+// Reply 200 with status "ok" when the check passes, 503 otherwise
+func writeProbe(w http.ResponseWriter, check string, ok bool) {
+	if ok {
+		writeJSON(w, http.StatusOK, ProbeResponse{Check: check, Status: "ok"})
+		return
+	}
+	writeJSON(w, http.StatusServiceUnavailable, ProbeResponse{Check: check, Status: "unavailable"})
+}
+
+// Liveness probe - 200 whenever the process can serve a request
+func livezHandler(w http.ResponseWriter, r *http.Request) {
+	writeProbe(w, "livez", true)
+}
+
+// Readiness probe - 503 until the server is listening and again while it shuts down
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	writeProbe(w, "readyz", serverReady.Load())
+}
+
+// Startup probe - 503 until startup has finished
+func startupzHandler(w http.ResponseWriter, r *http.Request) {
+	writeProbe(w, "startupz", serverStarted.Load())
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// Random generator shared by all handlers, reseeded from config at startup
+var rng = newRand(startTime.UnixNano())
+
+// lockedSource makes a rand.Source safe for concurrent handlers
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source64
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Uint64()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
+
+// Create a generator with a fixed seed so runs can be reproduced
+func newRand(seed int64) *rand.Rand {
+	return rand.New(&lockedSource{src: rand.NewSource(seed).(rand.Source64)})
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scenario represents a response scenario
+type Scenario struct {
+	Status   int
+	Error    string
+	Message  string
+	Details  string // may hold one %d placeholder, filled with a random ID per request
+	Location string
+	// Data builds the response data for a request, nil when there is none
+	Data func(delay int) interface{}
+}
+
+// All /api response scenarios, built once at startup
+var scenarios = []Scenario{
+	// 2xx Success responses
+	{200, "", "Success response", "", "", func(delay int) interface{} {
+		return map[string]interface{}{
+			"timestamp": time.Now().Format(time.RFC3339),
+			"delay":     fmt.Sprintf("%dms", delay),
+		}
+	}},
+	{200, "", "Data retrieved successfully", "", "", func(delay int) interface{} {
+		return map[string]interface{}{
+			"users": []string{"Alice", "Bob", "Charlie"},
+			"delay": fmt.Sprintf("%dms", delay),
+		}
+	}},
+	{200, "", "Search results found", "", "", func(delay int) interface{} {
+		return map[string]interface{}{
+			"results": []map[string]interface{}{
+				{"id": 1, "name": "Product A", "price": Price(29.99)},
+				{"id": 2, "name": "Product B", "price": Price(49.99)},
+			},
+			"total": 2,
+			"delay": fmt.Sprintf("%dms", delay),
+		}
+	}},
+	{201, "", "Resource created successfully", "", "", func(delay int) interface{} {
+		return map[string]interface{}{
+			"id":    rng.Intn(1000),
+			"delay": fmt.Sprintf("%dms", delay),
+		}
+	}},
+	{201, "", "User account created", "", "", func(delay int) interface{} {
+		return map[string]interface{}{
+			"userId":   rng.Intn(10000),
+			"username": fmt.Sprintf("user_%d", rng.Intn(1000)),
+			"delay":    fmt.Sprintf("%dms", delay),
+		}
+	}},
+	{202, "", "Request accepted for processing", "", "", func(delay int) interface{} {
+		return map[string]interface{}{
+			"jobId":  generateRequestID(),
+			"status": "queued",
+			"delay":  fmt.Sprintf("%dms", delay),
+		}
+	}},
+	{204, "", "No content - operation successful", "", "", nil},
+	{206, "", "Partial content delivered", "", "", func(delay int) interface{} {
+		return map[string]interface{}{
+			"range":         "bytes 0-1023/2048",
+			"contentLength": 1024,
+			"delay":         fmt.Sprintf("%dms", delay),
+		}
+	}},
+
+	// 3xx Redirection responses
+	{301, "", "Moved permanently", "", "/api/v2/endpoint", func(delay int) interface{} {
+		return map[string]interface{}{
+			"redirect": true,
+			"delay":    fmt.Sprintf("%dms", delay),
+		}
+	}},
+	{302, "", "Found - temporary redirect", "", "/api/temp-endpoint", func(delay int) interface{} {
+		return map[string]interface{}{
+			"redirect": true,
+			"delay":    fmt.Sprintf("%dms", delay),
+		}
+	}},
+	{304, "", "Not modified", "", "", func(delay int) interface{} {
+		return map[string]interface{}{
+			"cached": true,
+			"delay":  fmt.Sprintf("%dms", delay),
+		}
+	}},
+	{307, "", "Temporary redirect", "", "/api/v1/fallback", func(delay int) interface{} {
+		return map[string]interface{}{
+			"redirect": true,
+			"delay":    fmt.Sprintf("%dms", delay),
+		}
+	}},
+	{308, "", "Permanent redirect", "", "/api/v3/endpoint", func(delay int) interface{} {
+		return map[string]interface{}{
+			"redirect": true,
+			"delay":    fmt.Sprintf("%dms", delay),
+		}
+	}},
+
+	// 4xx Client error responses
+	{400, "Bad Request", "Invalid request parameters", "Missing required field 'email'", "", nil},
+	{400, "Bad Request", "Invalid JSON format", "Malformed JSON in request body", "", nil},
+	{401, "Unauthorized", "Authentication required", "Please provide a valid API key", "", nil},
+	{401, "Unauthorized", "Token expired", "JWT token has expired, please refresh", "", nil},
+	{402, "Payment Required", "Subscription expired", "Please upgrade your plan to continue", "", nil},
+	{403, "Forbidden", "Access denied", "Insufficient permissions for this resource", "", nil},
+	{403, "Forbidden", "IP address blocked", "Your IP has been temporarily blocked", "", nil},
+	{404, "Not Found", "Resource not found", "The requested endpoint does not exist", "", nil},
+	{404, "Not Found", "User not found", "User with ID %d does not exist", "", nil},
+	{405, "Method Not Allowed", "HTTP method not supported", "Only GET and POST methods are allowed", "", nil},
+	{406, "Not Acceptable", "Content type not acceptable", "Server cannot produce content matching Accept header", "", nil},
+	{408, "Request Timeout", "Request took too long", "Client did not send request within timeout period", "", nil},
+	{409, "Conflict", "Resource conflict", "Email address already exists", "", nil},
+	{410, "Gone", "Resource no longer available", "This API version has been deprecated", "", nil},
+	{411, "Length Required", "Content-Length header required", "Request must include Content-Length header", "", nil},
+	{412, "Precondition Failed", "Precondition not met", "If-Match header condition failed", "", nil},
+	{413, "Payload Too Large", "Request entity too large", "File size exceeds 10MB limit", "", nil},
+	{414, "URI Too Long", "Request URI too long", "URL exceeds maximum length of 2048 characters", "", nil},
+	{415, "Unsupported Media Type", "Media type not supported", "Content-Type 'text/plain' not supported", "", nil},
+	{416, "Range Not Satisfiable", "Requested range not satisfiable", "Range header specifies invalid byte range", "", nil},
+	{417, "Expectation Failed", "Expectation cannot be met", "Expect header requirements cannot be satisfied", "", nil},
+	{418, "I'm a teapot", "Cannot brew coffee", "This teapot cannot brew coffee (RFC 2324)", "", nil},
+	{421, "Misdirected Request", "Request misdirected", "Server cannot produce response for this request", "", nil},
+	{422, "Unprocessable Entity", "Validation failed", "Email format is invalid", "", nil},
+	{423, "Locked", "Resource is locked", "Resource is currently being modified by another process", "", nil},
+	{424, "Failed Dependency", "Dependent request failed", "Previous operation in sequence failed", "", nil},
+	{425, "Too Early", "Request sent too early", "Server unwilling to process replayed request", "", nil},
+	{426, "Upgrade Required", "Protocol upgrade required", "Client must upgrade to secure protocol", "", nil},
+	{428, "Precondition Required", "Precondition header required", "Request must include If-Match header", "", nil},
+	{429, "Too Many Requests", "Rate limit exceeded", "Maximum 100 requests per minute exceeded", "", nil},
+	{431, "Request Header Fields Too Large", "Headers too large", "Request headers exceed maximum size limit", "", nil},
+	{451, "Unavailable For Legal Reasons", "Content blocked", "Content unavailable due to legal restrictions", "", nil},
+
+	// 5xx Server error responses
+	{500, "Internal Server Error", "Something went wrong on our end", "Unexpected server error occurred", "", nil},
+	{500, "Internal Server Error", "Database connection failed", "Unable to connect to primary database", "", nil},
+	{501, "Not Implemented", "Feature not implemented", "This functionality is not yet available", "", nil},
+	{502, "Bad Gateway", "Upstream service unavailable", "Authentication service is not responding", "", nil},
+	{502, "Bad Gateway", "Invalid response from upstream", "Received malformed response from backend service", "", nil},
+	{503, "Service Unavailable", "Service temporarily unavailable", "Server is temporarily overloaded", "", nil},
+	{503, "Service Unavailable", "Maintenance mode", "Service under scheduled maintenance", "", nil},
+	{504, "Gateway Timeout", "Request timeout", "Upstream server did not respond within timeout", "", nil},
+	{505, "HTTP Version Not Supported", "HTTP version not supported", "Server does not support HTTP/2.0 protocol", "", nil},
+	{506, "Variant Also Negotiates", "Content negotiation error", "Server configuration error in content negotiation", "", nil},
+	{507, "Insufficient Storage", "Server storage full", "Unable to store representation needed for request", "", nil},
+	{508, "Loop Detected", "Infinite loop detected", "Server detected infinite loop while processing request", "", nil},
+	{510, "Not Extended", "Further extensions required", "Policy for accessing resource has not been met", "", nil},
+	{511, "Network Authentication Required", "Network authentication required", "Client needs to authenticate to gain network access", "", nil},
+
+	// Later additions go here rather than in their class above, so the ?scenario=
+	// index of every existing entry stays put for scripted tests
+	{300, "", "Multiple choices", "", "/api/v2/endpoint", func(delay int) interface{} {
+		return map[string]interface{}{
+			"alternatives": []map[string]string{
+				{"href": cfg.BasePath + "/api/v2/endpoint", "type": "application/json", "version": "v2"},
+				{"href": cfg.BasePath + "/api/v1/fallback", "type": "application/json", "version": "v1"},
+			},
+			"delay": fmt.Sprintf("%dms", delay),
+		}
+	}},
+	{303, "", "See other - result available", "", "/api/results/latest", func(delay int) interface{} {
+		return map[string]interface{}{
+			"redirect":  true,
+			"resultUrl": cfg.BasePath + "/api/results/latest",
+			"delay":     fmt.Sprintf("%dms", delay),
+		}
+	}},
+}
+
+// Scenarios grouped by response class
+var (
+	successScenarios     = scenariosInRange(200, 300)
+	redirectScenarios    = scenariosInRange(300, 400)
+	clientErrorScenarios = scenariosInRange(400, 500)
+	serverErrorScenarios = scenariosInRange(500, 600)
+)
+
+// Collect catalog scenarios with a status in [min, max)
+func scenariosInRange(min, max int) []Scenario {
+	var matching []Scenario
+	for _, s := range scenarios {
+		if s.Status >= min && s.Status < max {
+			matching = append(matching, s)
+		}
+	}
+	return matching
+}
+
+// Details for one response, drawing any placeholder ID from r at request time
+func (s Scenario) detailsFor(r *rand.Rand) string {
+	if !strings.Contains(s.Details, "%d") {
+		return s.Details
+	}
+	return fmt.Sprintf(s.Details, r.Intn(1000))
+}
+
+// Low-cardinality status class such as "2xx" or "5xx"
+func statusClass(code int) string {
+	if code < 100 || code > 599 {
+		return "unknown"
+	}
+	return fmt.Sprintf("%dxx", code/100)
+}
+
+// Pick a response class by weight, then a random scenario within that class
+func selectScenario(weights ScenarioWeights, r *rand.Rand) Scenario {
+	classes := []struct {
+		weight    int
+		scenarios []Scenario
+	}{
+		{weights.Success, successScenarios},
+		{weights.Redirect, redirectScenarios},
+		{weights.ClientError, clientErrorScenarios},
+		{weights.ServerError, serverErrorScenarios},
+	}
+
+	total := 0
+	for _, c := range classes {
+		if len(c.scenarios) > 0 {
+			total += c.weight
+		}
+	}
+
+	if total > 0 {
+		n := r.Intn(total)
+		for _, c := range classes {
+			if len(c.scenarios) == 0 {
+				continue
+			}
+			if n < c.weight {
+				return c.scenarios[r.Intn(len(c.scenarios))]
+			}
+			n -= c.weight
+		}
+	}
+
+	// Weights are validated on load, fall back to the whole catalog just in case
+	return scenarios[r.Intn(len(scenarios))]
+}
+
+// Look up the scenario requested via query parameters, nil when none was requested
+func requestedScenario(r *http.Request) (*Scenario, error) {
+	query := r.URL.Query()
+
+	if query.Get("scenario") != "" && query.Get("status") != "" {
+		return nil, fmt.Errorf("use either scenario or status, not both")
+	}
+
+	if value := query.Get("scenario"); value != "" {
+		index, err := strconv.Atoi(value)
+		if err != nil || index < 0 || index >= len(scenarios) {
+			return nil, fmt.Errorf("scenario must be an index between 0 and %d", len(scenarios)-1)
+		}
+		return &scenarios[index], nil
+	}
+
+	if value := query.Get("status"); value != "" {
+		status, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("status must be a number, got %q", value)
+		}
+
+		// Several scenarios can share a status code, pick one of them at random
+		var matching []Scenario
+		for _, s := range scenarios {
+			if s.Status == status {
+				matching = append(matching, s)
+			}
+		}
+		if len(matching) == 0 {
+			return nil, fmt.Errorf("no scenario with status %d", status)
+		}
+		return &matching[rng.Intn(len(matching))], nil
+	}
+
+	return nil, nil
+}
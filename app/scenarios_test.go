@@ -0,0 +1,96 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSelectScenarioDistribution(t *testing.T) {
+	weights := ScenarioWeights{Success: 70, Redirect: 10, ClientError: 15, ServerError: 5}
+	r := rand.New(rand.NewSource(1))
+
+	const draws = 20000
+	counts := map[string]int{}
+	for i := 0; i < draws; i++ {
+		counts[statusClass(selectScenario(weights, r).Status)]++
+	}
+
+	want := map[string]int{"2xx": 70, "3xx": 10, "4xx": 15, "5xx": 5}
+	for class, weight := range want {
+		got := float64(counts[class]) / draws * 100
+		if math.Abs(got-float64(weight)) > 1.5 {
+			t.Errorf("class %s drawn %.1f%% of the time, want about %d%%", class, got, weight)
+		}
+	}
+}
+
+func TestSelectScenarioSingleClass(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		if s := selectScenario(ScenarioWeights{ServerError: 1}, r); statusClass(s.Status) != "5xx" {
+			t.Fatalf("got status %d with only server errors weighted", s.Status)
+		}
+	}
+}
+
+func TestSelectScenarioZeroWeightsFallback(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	seen := map[string]bool{}
+	for i := 0; i < 1000; i++ {
+		seen[statusClass(selectScenario(ScenarioWeights{}, r).Status)] = true
+	}
+
+	for _, class := range []string{"2xx", "3xx", "4xx", "5xx"} {
+		if !seen[class] {
+			t.Errorf("zero weights never fell back to a %s scenario", class)
+		}
+	}
+}
+
+func TestSelectScenarioReproducible(t *testing.T) {
+	weights := ScenarioWeights{Success: 40, Redirect: 5, ClientError: 30, ServerError: 25}
+	a, b := rand.New(rand.NewSource(42)), rand.New(rand.NewSource(42))
+	for i := 0; i < 100; i++ {
+		if sa, sb := selectScenario(weights, a), selectScenario(weights, b); sa.Message != sb.Message {
+			t.Fatalf("draw %d differs with the same seed: %q vs %q", i, sa.Message, sb.Message)
+		}
+	}
+}
+
+func TestDetailsForFillsPlaceholderPerRequest(t *testing.T) {
+	s := Scenario{Status: 404, Details: "User with ID %d does not exist"}
+
+	a, b := rand.New(rand.NewSource(7)), rand.New(rand.NewSource(7))
+	if da, db := s.detailsFor(a), s.detailsFor(b); da != db {
+		t.Errorf("same seed gave different details: %q vs %q", da, db)
+	}
+
+	r := rand.New(rand.NewSource(7))
+	seen := map[string]bool{}
+	for i := 0; i < 20; i++ {
+		details := s.detailsFor(r)
+		if strings.Contains(details, "%d") {
+			t.Fatalf("placeholder left in details: %q", details)
+		}
+		seen[details] = true
+	}
+	if len(seen) < 2 {
+		t.Error("user ID did not vary between requests")
+	}
+
+	plain := Scenario{Details: "Missing required field 'email'"}
+	if got := plain.detailsFor(r); got != plain.Details {
+		t.Errorf("details without a placeholder changed to %q", got)
+	}
+}
+
+func TestRequestedScenarioRejectsBothParameters(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api?scenario=0&status=200", nil)
+	if s, err := requestedScenario(req); err == nil {
+		t.Errorf("got scenario %+v, want an error when both scenario and status are set", s)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Price is a monetary amount that always encodes as a JSON number with two decimals
+type Price float64
+
+func (p Price) MarshalJSON() ([]byte, error) {
+	return strconv.AppendFloat(nil, float64(p), 'f', 2, 64), nil
+}
+
+type Product struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Price Price  `json:"price"`
+}
+
+// Catalog searched by /api/search
+var products = []Product{
+	{1, "Product A", 29.99},
+	{2, "Product B", 49.99},
+	{3, "Wireless Mouse", 19.99},
+	{4, "Mechanical Keyboard", 89.99},
+	{5, "USB-C Hub", 39.99},
+	{6, "Laptop Stand", 24.99},
+	{7, "Noise Cancelling Headphones", 199.99},
+	{8, "Webcam", 59.99},
+}
+
+// Check ?q=, ?minPrice= and ?maxPrice=, collecting every problem rather than stopping at the first.
+// Missing prices leave that side of the range open.
+func parseSearchQuery(r *http.Request) (q string, minPrice, maxPrice float64, errs []FieldError) {
+	query := r.URL.Query()
+
+	q = strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		errs = append(errs, FieldError{Field: "q", Message: "is required"})
+	}
+
+	parsePrice := func(field string, fallback float64) float64 {
+		value := query.Get(field)
+		if value == "" {
+			return fallback
+		}
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil || price < 0 || math.IsNaN(price) || math.IsInf(price, 0) {
+			errs = append(errs, FieldError{Field: field, Message: "must be a non-negative number"})
+			return fallback
+		}
+		return price
+	}
+	minPrice = parsePrice("minPrice", 0)
+	maxPrice = parsePrice("maxPrice", -1)
+
+	if maxPrice >= 0 && minPrice > maxPrice {
+		errs = append(errs, FieldError{Field: "minPrice", Message: "must not be greater than maxPrice"})
+	}
+	return q, minPrice, maxPrice, errs
+}
+
+// Search handler - validated product search, 422 with one entry per invalid field
+func searchHandler(w http.ResponseWriter, r *http.Request) {
+	requestID := requestIDFromContext(r.Context())
+
+	q, minPrice, maxPrice, fieldErrors := parseSearchQuery(r)
+	if len(fieldErrors) > 0 {
+		slog.Warn("Invalid search query", "errors", fieldErrors, "request_id", requestID)
+
+		response := newErrorResponse(http.StatusUnprocessableEntity, "Unprocessable Entity", "Validation failed",
+			"One or more query parameters are invalid", requestID)
+		response.Errors = fieldErrors
+		writeJSON(w, http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	results := []Product{}
+	for _, p := range products {
+		if !strings.Contains(strings.ToLower(p.Name), strings.ToLower(q)) {
+			continue
+		}
+		if price := float64(p.Price); price < minPrice || (maxPrice >= 0 && price > maxPrice) {
+			continue
+		}
+		results = append(results, p)
+	}
+
+	slog.Info("Search completed", "q", q, "results", len(results), "request_id", requestID)
+
+	writeJSON(w, http.StatusOK, SuccessResponse{
+		Status:  http.StatusOK,
+		Message: "Search results found",
+		Data: map[string]interface{}{
+			"results": results,
+			"total":   len(results),
+		},
+		RequestID: requestID,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
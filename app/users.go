@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type User struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+const (
+	defaultUsersLimit = 10
+	maxUsersLimit     = 100
+)
+
+// Fixed dataset behind /api/users, the same on every run so pages are stable
+var users = generateUsers(57)
+
+func generateUsers(n int) []User {
+	first := []string{"Alice", "Bob", "Charlie", "Diana", "Ethan", "Fiona", "George", "Hannah"}
+	last := []string{"Smith", "Jones", "Garcia", "Chen", "Novak", "Okafor", "Silva"}
+
+	list := make([]User, n)
+	for i := range list {
+		name := first[i%len(first)] + " " + last[i%len(last)]
+		list[i] = User{
+			ID:    i + 1,
+			Name:  name,
+			Email: fmt.Sprintf("%s.%d@example.com", strings.ToLower(strings.ReplaceAll(name, " ", ".")), i+1),
+		}
+	}
+	return list
+}
+
+// Read ?page= and ?limit=, defaulting to the first page of defaultUsersLimit users
+func parsePagination(r *http.Request) (page, limit int, err error) {
+	page, limit = 1, defaultUsersLimit
+	query := r.URL.Query()
+
+	if value := query.Get("page"); value != "" {
+		if page, err = strconv.Atoi(value); err != nil || page < 1 {
+			return 0, 0, fmt.Errorf("page must be a positive integer, got %q", value)
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > maxUsersLimit {
+			return 0, 0, fmt.Errorf("limit must be an integer between 1 and %d, got %q", maxUsersLimit, value)
+		}
+	}
+	return page, limit, nil
+}
+
+// Users handler - paginated list driven by ?page= and ?limit=, empty past the last page
+func usersHandler(w http.ResponseWriter, r *http.Request) {
+	requestID := requestIDFromContext(r.Context())
+	timestamp := time.Now().Format(time.RFC3339)
+
+	page, limit, err := parsePagination(r)
+	if err != nil {
+		slog.Warn("Invalid pagination parameters", "error", err, "request_id", requestID)
+
+		writeError(w, http.StatusBadRequest, "Bad Request", "Invalid pagination parameters", err.Error(), requestID)
+		return
+	}
+
+	// Compare pages before multiplying so a huge ?page= can't overflow the offset
+	totalPages := (len(users) + limit - 1) / limit
+	start := len(users)
+	if page <= totalPages {
+		start = (page - 1) * limit
+	}
+	end := min(start+limit, len(users))
+
+	slog.Info("Users listed", "page", page, "limit", limit, "returned", end-start, "request_id", requestID)
+
+	writeJSON(w, http.StatusOK, SuccessResponse{
+		Status:  http.StatusOK,
+		Message: "Data retrieved successfully",
+		Data: map[string]interface{}{
+			"users":      users[start:end],
+			"total":      len(users),
+			"page":       page,
+			"limit":      limit,
+			"totalPages": totalPages,
+		},
+		RequestID: requestID,
+		Timestamp: timestamp,
+	})
+}